@@ -0,0 +1,103 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/unixlamadev-spec/lightningprox-mcp/internal/ledger"
+)
+
+func TestFilterLedgerEntries(t *testing.T) {
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	entries := []ledger.Entry{
+		{Timestamp: t0, Model: "claude-sonnet-4-20250514", Status: "success", Metadata: map[string]string{"tenant": "acme"}},
+		{Timestamp: t0.Add(time.Hour), Model: "gpt-4-turbo", Status: "error", Metadata: map[string]string{"tenant": "other"}},
+		{Timestamp: t0.Add(2 * time.Hour), Model: "claude-sonnet-4-20250514", Status: "success", Metadata: nil},
+	}
+
+	tests := []struct {
+		name                            string
+		since, until                    time.Time
+		model, status, metaKey, metaVal string
+		want                            int
+	}{
+		{name: "no filters", want: 3},
+		{name: "by model", model: "claude-sonnet-4-20250514", want: 2},
+		{name: "by status", status: "error", want: 1},
+		{name: "since excludes earlier entries", since: t0.Add(30 * time.Minute), want: 2},
+		{name: "until excludes entries at or after it", until: t0.Add(time.Hour), want: 1},
+		{name: "meta key present", metaKey: "tenant", want: 2},
+		{name: "meta key and value", metaKey: "tenant", metaVal: "acme", want: 1},
+		{name: "meta key absent on entry excludes it", metaKey: "missing", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := filterLedgerEntries(entries, tt.since, tt.until, tt.model, tt.status, tt.metaKey, tt.metaVal)
+			if len(got) != tt.want {
+				t.Errorf("filterLedgerEntries() = %d entries, want %d", len(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestSpendGroupKey(t *testing.T) {
+	e := ledger.Entry{
+		Model:    "gpt-4-turbo",
+		Status:   "success",
+		Metadata: map[string]string{"agent_id": "agent-1"},
+	}
+
+	tests := []struct {
+		groupBy string
+		want    string
+	}{
+		{"model", "gpt-4-turbo"},
+		{"status", "success"},
+		{"meta:agent_id", "agent-1"},
+		{"meta:missing", ""},
+		{"unrecognized", ""},
+	}
+	for _, tt := range tests {
+		if got := spendGroupKey(e, tt.groupBy); got != tt.want {
+			t.Errorf("spendGroupKey(%q) = %q, want %q", tt.groupBy, got, tt.want)
+		}
+	}
+}
+
+func TestResponseUsageTokens(t *testing.T) {
+	tests := []struct {
+		name           string
+		respData       map[string]interface{}
+		wantIn, wantOut int
+	}{
+		{
+			name: "anthropic usage",
+			respData: map[string]interface{}{
+				"usage": map[string]interface{}{"input_tokens": float64(12), "output_tokens": float64(34)},
+			},
+			wantIn: 12, wantOut: 34,
+		},
+		{
+			name: "openai usage",
+			respData: map[string]interface{}{
+				"usage": map[string]interface{}{"prompt_tokens": float64(5), "completion_tokens": float64(7)},
+			},
+			wantIn: 5, wantOut: 7,
+		},
+		{
+			name:     "no usage block",
+			respData: map[string]interface{}{},
+			wantIn:   0, wantOut: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotIn, gotOut := responseUsageTokens(tt.respData)
+			if gotIn != tt.wantIn || gotOut != tt.wantOut {
+				t.Errorf("responseUsageTokens() = (%d, %d), want (%d, %d)", gotIn, gotOut, tt.wantIn, tt.wantOut)
+			}
+		})
+	}
+}
@@ -3,16 +3,26 @@ package main
 import (
 	"bytes"
 	"context"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"os"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/unixlamadev-spec/lightningprox-mcp/internal/ledger"
+	"github.com/unixlamadev-spec/lightningprox-mcp/internal/models"
+	"github.com/unixlamadev-spec/lightningprox-mcp/internal/payments"
+	"github.com/unixlamadev-spec/lightningprox-mcp/internal/swap"
+	"github.com/unixlamadev-spec/lightningprox-mcp/internal/swapstate"
+	"github.com/unixlamadev-spec/lightningprox-mcp/internal/wallet"
 )
 
 // ============================================================================
@@ -25,34 +35,73 @@ const (
 	DefaultLightningProxURL = "https://lightningprox.com"
 	ServerName              = "lightningprox-mcp"
 	ServerVersion           = "1.0.0"
+
+	// Defaults for autopay when the payment backend doesn't enforce its own.
+	defaultFeeLimitSat = 10
+	defaultCLTVLimit   = 144
+	autopayPollTimeout = 60 * time.Second
 )
 
+// walletBackend is the configured Lightning node used for autopay, or nil
+// when LIGHTNING_BACKEND is unset/"none".
+var walletBackend wallet.Backend
+
+// budget enforces MAX_SATS_PER_CALL / DAILY_SATS_BUDGET across autopay
+// payments. Always non-nil; ceilings of 0 mean "no limit".
+var budget *wallet.BudgetTracker
+
+// paymentStore tracks charge lifecycle across restarts. Always non-nil.
+var paymentStore *payments.Store
+
+// swapProvider is the configured on-chain-to-Lightning swap service, or nil
+// when SWAP_PROVIDER_URL is unset.
+var swapProvider swap.Provider
+
+// spendLedger records every charge (with caller-supplied metadata) for
+// cost-attribution queries and CSV export. Always non-nil.
+var spendLedger *ledger.Ledger
+
+// swapCharges maps a swap_id (from submit_swap/pay_via_swap) to the
+// paymentStore charge_id it's settling, so check_swap_status can transition
+// that charge out of StateCreated once the swap provider reports it paid.
+// Persisted to disk: on-chain confirmations often take far longer than a
+// server restart, and without this link a swap that completes after one
+// could never settle its charge. Always non-nil.
+var swapCharges *swapstate.Store
+
+// progressPollInterval is how often track_payment re-checks the store.
+const progressPollInterval = 2 * time.Second
+
 // --- Tool Input/Output Types ---
 
 // AskAIInput is the input for the ask_ai tool
 type AskAIInput struct {
-	Model       string `json:"model" jsonschema:"description=The AI model to use (e.g. claude-sonnet-4-20250514 or gpt-4o)"`
-	Prompt      string `json:"prompt" jsonschema:"description=The message or prompt to send to the AI model"`
-	MaxTokens   int    `json:"max_tokens,omitempty" jsonschema:"description=Maximum tokens in the response (default 1024)"`
-	PaymentHash string `json:"payment_hash,omitempty" jsonschema:"description=Payment hash from a previously paid invoice. If not provided a new invoice will be generated."`
-	SpendToken  string `json:"spend_token,omitempty" jsonschema:"description=Prepaid spend token for balance-based access. Overrides payment_hash if provided."`
+	Model       string            `json:"model" jsonschema:"description=The AI model to use (e.g. claude-sonnet-4-20250514 or gpt-4o)"`
+	Prompt      string            `json:"prompt" jsonschema:"description=The message or prompt to send to the AI model"`
+	MaxTokens   int               `json:"max_tokens,omitempty" jsonschema:"description=Maximum tokens in the response (default 1024)"`
+	PaymentHash string            `json:"payment_hash,omitempty" jsonschema:"description=Payment hash from a previously paid invoice. If not provided a new invoice will be generated."`
+	SpendToken  string            `json:"spend_token,omitempty" jsonschema:"description=Prepaid spend token for balance-based access. Overrides payment_hash if provided."`
+	Metadata    map[string]string `json:"metadata,omitempty" jsonschema:"description=Arbitrary tags (e.g. agent_id, task_id, tenant) attached to this charge for cost attribution. Forwarded to LightningProx as X-Metadata and recorded in the local spend ledger."`
 }
 
 type AskAIOutput struct {
-	Response    string `json:"response,omitempty"`
-	ChargeID    string `json:"charge_id,omitempty"`
-	PaymentReq  string `json:"payment_request,omitempty"`
-	AmountSats  int    `json:"amount_sats,omitempty"`
-	AmountUSD   float64 `json:"amount_usd,omitempty"`
-	Status      string `json:"status"`
-	Error       string `json:"error,omitempty"`
+	Response   string  `json:"response,omitempty"`
+	ChargeID   string  `json:"charge_id,omitempty"`
+	PaymentReq string  `json:"payment_request,omitempty"`
+	AmountSats int     `json:"amount_sats,omitempty"`
+	AmountUSD  float64 `json:"amount_usd,omitempty"`
+	Status     string  `json:"status"`
+	Error      string  `json:"error,omitempty"`
+	// ModelUsed is set by ask_ai_auto to report which candidate it dispatched to.
+	ModelUsed string `json:"model_used,omitempty"`
 }
 
 // GetInvoiceInput generates a Lightning invoice for prepaid access
 type GetInvoiceInput struct {
-	Model     string `json:"model" jsonschema:"description=The AI model to generate an invoice for"`
-	MaxTokens int    `json:"max_tokens,omitempty" jsonschema:"description=Expected max tokens (affects invoice amount)"`
-	Prompt    string `json:"prompt" jsonschema:"description=The prompt you intend to send (used to estimate cost)"`
+	Model     string            `json:"model" jsonschema:"description=The AI model to generate an invoice for"`
+	MaxTokens int               `json:"max_tokens,omitempty" jsonschema:"description=Expected max tokens (affects invoice amount)"`
+	Prompt    string            `json:"prompt" jsonschema:"description=The prompt you intend to send (used to estimate cost)"`
+	Metadata  map[string]string `json:"metadata,omitempty" jsonschema:"description=Arbitrary tags (e.g. agent_id, task_id, tenant) attached to this charge for cost attribution. Forwarded to LightningProx as X-Metadata and recorded in the local spend ledger."`
 }
 
 type GetInvoiceOutput struct {
@@ -70,22 +119,23 @@ type CheckBalanceInput struct {
 }
 
 type CheckBalanceOutput struct {
-	BalanceSats  int    `json:"balance_sats"`
+	BalanceSats  int     `json:"balance_sats"`
 	BalanceUSD   float64 `json:"balance_usd"`
-	RequestsLeft int    `json:"requests_left_estimate"`
-	ExpiresAt    string `json:"expires_at"`
-	Status       string `json:"status"`
+	RequestsLeft int     `json:"requests_left_estimate"`
+	ExpiresAt    string  `json:"expires_at"`
+	Status       string  `json:"status"`
 }
 
 // ListModelsInput (no params needed)
 type ListModelsInput struct{}
 
 type ModelInfo struct {
-	ID          string  `json:"id"`
-	Provider    string  `json:"provider"`
-	InputCost   float64 `json:"input_cost_per_1k_tokens"`
-	OutputCost  float64 `json:"output_cost_per_1k_tokens"`
-	MaxContext  int     `json:"max_context_tokens"`
+	ID           string  `json:"id"`
+	Provider     string  `json:"provider"`
+	InputCost    float64 `json:"input_cost_per_1k_tokens"`
+	OutputCost   float64 `json:"output_cost_per_1k_tokens"`
+	MaxContext   int     `json:"max_context_tokens"`
+	QualityScore float64 `json:"quality_score,omitempty"`
 }
 
 type ListModelsOutput struct {
@@ -107,6 +157,228 @@ type GetPricingOutput struct {
 	Markup          string  `json:"markup"`
 }
 
+// WalletInfoInput (no params needed)
+type WalletInfoInput struct{}
+
+type WalletInfoOutput struct {
+	Enabled         bool   `json:"enabled"`
+	Backend         string `json:"backend,omitempty"`
+	Alias           string `json:"alias,omitempty"`
+	BlockHeight     uint32 `json:"block_height,omitempty"`
+	SyncedToChain   bool   `json:"synced_to_chain,omitempty"`
+	RemainingBudget int    `json:"remaining_daily_budget_sats"`
+	Status          string `json:"status"`
+	Error           string `json:"error,omitempty"`
+}
+
+// WalletPayInvoiceInput pays an arbitrary BOLT11 invoice from the configured wallet
+type WalletPayInvoiceInput struct {
+	PaymentRequest string `json:"payment_request" jsonschema:"description=The BOLT11 invoice to pay"`
+	AmountSats     int    `json:"amount_sats" jsonschema:"description=Invoice amount in sats, used to check MAX_SATS_PER_CALL and DAILY_SATS_BUDGET before paying"`
+	FeeLimitSat    int    `json:"fee_limit_sat,omitempty" jsonschema:"description=Maximum routing fee to pay in sats (default 10)"`
+	CLTVLimit      int    `json:"cltv_limit,omitempty" jsonschema:"description=Maximum acceptable route timelock in blocks (default 144)"`
+}
+
+type WalletPayInvoiceOutput struct {
+	Preimage string `json:"preimage,omitempty"`
+	FeeSat   int    `json:"fee_sat,omitempty"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+}
+
+// WalletSetBudgetInput overrides the in-process budget ceilings
+type WalletSetBudgetInput struct {
+	MaxSatsPerCall  int `json:"max_sats_per_call" jsonschema:"description=New per-call ceiling in sats (0 means no limit)"`
+	DailySatsBudget int `json:"daily_sats_budget" jsonschema:"description=New daily ceiling in sats (0 means no limit)"`
+}
+
+type WalletSetBudgetOutput struct {
+	MaxSatsPerCall  int    `json:"max_sats_per_call"`
+	DailySatsBudget int    `json:"daily_sats_budget"`
+	Status          string `json:"status"`
+}
+
+// TrackPaymentInput polls a tracked charge until it reaches a terminal state
+type TrackPaymentInput struct {
+	ChargeID string `json:"charge_id" jsonschema:"description=The charge_id to track (from ask_ai or get_invoice)"`
+}
+
+type TrackPaymentOutput struct {
+	ChargeID string `json:"charge_id"`
+	State    string `json:"state"`
+	Attempts int    `json:"attempts"`
+	LastErr  string `json:"last_err,omitempty"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+}
+
+// ListPaymentsInput filters the tracked payment ledger
+type ListPaymentsInput struct {
+	State string `json:"state,omitempty" jsonschema:"description=Only return charges in this state (created, in_flight, succeeded, failed, expired)"`
+}
+
+type PaymentSummary struct {
+	ChargeID   string  `json:"charge_id"`
+	State      string  `json:"state"`
+	Model      string  `json:"model,omitempty"`
+	AmountSats int     `json:"amount_sats,omitempty"`
+	AmountUSD  float64 `json:"amount_usd,omitempty"`
+	Attempts   int     `json:"attempts"`
+	Consumed   bool    `json:"consumed"`
+	FirstSeen  string  `json:"first_seen"`
+	SettledAt  string  `json:"settled_at,omitempty"`
+}
+
+type ListPaymentsOutput struct {
+	Payments []PaymentSummary `json:"payments"`
+}
+
+// ResumePaymentInput re-invokes ask_ai using a settled, unconsumed payment_hash
+type ResumePaymentInput struct {
+	ChargeID  string `json:"charge_id" jsonschema:"description=A charge_id that was settled but never consumed"`
+	Model     string `json:"model" jsonschema:"description=The AI model to use, must match the original request"`
+	Prompt    string `json:"prompt" jsonschema:"description=The prompt originally sent for this charge"`
+	MaxTokens int    `json:"max_tokens,omitempty" jsonschema:"description=Maximum tokens in the response (default 1024)"`
+}
+
+// GetSwapQuoteInput estimates the cost of a loop-in swap for amountSats
+type GetSwapQuoteInput struct {
+	AmountSats int `json:"amount_sats" jsonschema:"description=The off-chain amount (e.g. the invoice's amount_sats) to swap on-chain BTC into"`
+}
+
+type GetSwapQuoteOutput struct {
+	AmountSats       int    `json:"amount_sats"`
+	RoutingBufferSat int    `json:"routing_buffer_sat"`
+	EstimatedFeeSat  int    `json:"estimated_fee_sat"`
+	TotalSat         int    `json:"total_sat"`
+	Status           string `json:"status"`
+	Error            string `json:"error,omitempty"`
+}
+
+// SubmitSwapInput creates a loop-in swap that will pay paymentRequest once funded on-chain
+type SubmitSwapInput struct {
+	PaymentRequest string `json:"payment_request" jsonschema:"description=The BOLT11 invoice the swap provider should pay once the on-chain HTLC confirms"`
+	AmountSats     int    `json:"amount_sats" jsonschema:"description=The invoice amount in sats"`
+}
+
+type SubmitSwapOutput struct {
+	SwapID         string `json:"swap_id,omitempty"`
+	Address        string `json:"address,omitempty"`
+	ExpectedFeeSat int    `json:"expected_fee_sat,omitempty"`
+	ExpiresAt      string `json:"expires_at,omitempty"`
+	Status         string `json:"status"`
+	Error          string `json:"error,omitempty"`
+}
+
+// CheckSwapStatusInput polls a previously submitted swap
+type CheckSwapStatusInput struct {
+	SwapID string `json:"swap_id" jsonschema:"description=The swap_id returned by submit_swap or pay_via_swap"`
+}
+
+type CheckSwapStatusOutput struct {
+	SwapID   string `json:"swap_id"`
+	State    string `json:"state,omitempty"`
+	Preimage string `json:"preimage,omitempty"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+}
+
+// PayViaSwapInput buys AI inference using on-chain BTC via a submarine swap
+type PayViaSwapInput struct {
+	Model     string `json:"model" jsonschema:"description=The AI model to use"`
+	Prompt    string `json:"prompt" jsonschema:"description=The prompt to send once the invoice is paid"`
+	MaxTokens int    `json:"max_tokens,omitempty" jsonschema:"description=Maximum tokens in the response (default 1024)"`
+}
+
+type PayViaSwapOutput struct {
+	ChargeID       string  `json:"charge_id,omitempty"`
+	SwapID         string  `json:"swap_id,omitempty"`
+	Address        string  `json:"address,omitempty"`
+	ExpectedFeeSat int     `json:"expected_fee_sat,omitempty"`
+	AmountSats     int     `json:"amount_sats,omitempty"`
+	AmountUSD      float64 `json:"amount_usd,omitempty"`
+	ExpiresAt      string  `json:"expires_at,omitempty"`
+	Status         string  `json:"status"`
+	Error          string  `json:"error,omitempty"`
+}
+
+// SuggestModelInput asks for ranked model candidates under a set of constraints
+type SuggestModelInput struct {
+	Prompt             string   `json:"prompt" jsonschema:"description=The prompt to be sent, used for context-fit and cost estimation"`
+	MaxTokens          int      `json:"max_tokens,omitempty" jsonschema:"description=Expected output tokens (default 1024)"`
+	QualityTier        string   `json:"quality_tier,omitempty" jsonschema:"description=Minimum quality tier: economy, balanced, or premium (default economy, i.e. no minimum)"`
+	MaxSats            int      `json:"max_sats,omitempty" jsonschema:"description=Exclude candidates estimated to cost more than this many sats"`
+	MaxUSD             float64  `json:"max_usd,omitempty" jsonschema:"description=Exclude candidates estimated to cost more than this many USD"`
+	PreferredProviders []string `json:"preferred_providers,omitempty" jsonschema:"description=If set, only consider models from these providers (e.g. anthropic, openai)"`
+	ExcludedProviders  []string `json:"excluded_providers,omitempty" jsonschema:"description=Never consider models from these providers"`
+}
+
+type ModelCandidate struct {
+	Model         string  `json:"model"`
+	Provider      string  `json:"provider"`
+	EstimatedSats int     `json:"estimated_sats"`
+	EstimatedUSD  float64 `json:"estimated_usd"`
+	QualityScore  float64 `json:"quality_score"`
+	Reason        string  `json:"reason"`
+}
+
+type SuggestModelOutput struct {
+	Candidates []ModelCandidate `json:"candidates"`
+}
+
+// AskAIAutoInput is ask_ai with suggest_model's constraints instead of a fixed model
+type AskAIAutoInput struct {
+	Prompt             string   `json:"prompt" jsonschema:"description=The message or prompt to send to the AI model"`
+	MaxTokens          int      `json:"max_tokens,omitempty" jsonschema:"description=Maximum tokens in the response (default 1024)"`
+	QualityTier        string   `json:"quality_tier,omitempty" jsonschema:"description=Minimum quality tier: economy, balanced, or premium (default economy)"`
+	MaxSats            int      `json:"max_sats,omitempty" jsonschema:"description=Exclude candidates estimated to cost more than this many sats"`
+	MaxUSD             float64  `json:"max_usd,omitempty" jsonschema:"description=Exclude candidates estimated to cost more than this many USD"`
+	PreferredProviders []string `json:"preferred_providers,omitempty" jsonschema:"description=If set, only consider models from these providers"`
+	ExcludedProviders  []string `json:"excluded_providers,omitempty" jsonschema:"description=Never consider models from these providers"`
+}
+
+// QuerySpendInput filters the local spend ledger and aggregates the result.
+type QuerySpendInput struct {
+	Since         string `json:"since,omitempty" jsonschema:"description=RFC3339 timestamp; only include charges recorded at or after this time"`
+	Until         string `json:"until,omitempty" jsonschema:"description=RFC3339 timestamp; only include charges recorded before this time"`
+	Model         string `json:"model,omitempty" jsonschema:"description=Only include charges for this model"`
+	Status        string `json:"status,omitempty" jsonschema:"description=Only include charges with this status (e.g. success, error)"`
+	MetadataKey   string `json:"metadata_key,omitempty" jsonschema:"description=Only include charges that have this metadata key set"`
+	MetadataValue string `json:"metadata_value,omitempty" jsonschema:"description=If metadata_key is set, further restrict to charges where that key equals this value"`
+	GroupBy       string `json:"group_by,omitempty" jsonschema:"description=Aggregate totals by this field: model, status, or a metadata key prefixed with meta: (e.g. meta:tenant)"`
+}
+
+// SpendGroup is one bucket of a group_by aggregation.
+type SpendGroup struct {
+	Key       string  `json:"key"`
+	Count     int     `json:"count"`
+	TotalSats int     `json:"total_sats"`
+	TotalUSD  float64 `json:"total_usd"`
+}
+
+type QuerySpendOutput struct {
+	Count     int          `json:"count"`
+	TotalSats int          `json:"total_sats"`
+	TotalUSD  float64      `json:"total_usd"`
+	Groups    []SpendGroup `json:"groups,omitempty"`
+	Status    string       `json:"status"`
+	Error     string       `json:"error,omitempty"`
+}
+
+// ExportSpendCSVInput filters the local spend ledger for a CSV export.
+type ExportSpendCSVInput struct {
+	Since string `json:"since,omitempty" jsonschema:"description=RFC3339 timestamp; only include charges recorded at or after this time"`
+	Until string `json:"until,omitempty" jsonschema:"description=RFC3339 timestamp; only include charges recorded before this time"`
+	Model string `json:"model,omitempty" jsonschema:"description=Only include charges for this model"`
+}
+
+type ExportSpendCSVOutput struct {
+	CSV    string `json:"csv"`
+	Count  int    `json:"count"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
 // --- HTTP Client Helpers ---
 
 func getLightningProxURL() string {
@@ -183,10 +455,21 @@ func handleAskAI(ctx context.Context, req *mcp.CallToolRequest, input AskAIInput
 	} else if input.PaymentHash != "" {
 		headers["X-Payment-Hash"] = input.PaymentHash
 	}
+	if len(input.Metadata) > 0 {
+		if metaJSON, err := json.Marshal(input.Metadata); err == nil {
+			headers["X-Metadata"] = string(metaJSON)
+		}
+	}
 
 	// All models go through /v1/messages on LightningProx
 	endpoint := "/v1/messages"
 
+	// ledgerChargeID/ledgerAmountSats/ledgerAmountUSD carry whatever charge
+	// info this call resolves, for the spend-ledger entry recorded below.
+	var ledgerChargeID string
+	var ledgerAmountSats int
+	var ledgerAmountUSD float64
+
 	respBody, statusCode, err := makeRequest("POST", baseURL+endpoint, requestBody, headers)
 	if err != nil {
 		return nil, AskAIOutput{Status: "error", Error: err.Error()}, nil
@@ -198,7 +481,8 @@ func handleAskAI(ctx context.Context, req *mcp.CallToolRequest, input AskAIInput
 		return nil, AskAIOutput{Status: "error", Error: "failed to parse response"}, nil
 	}
 
-	// If payment required (402), return invoice details
+	// If payment required (402), either autopay (when a wallet backend is
+	// configured) or return the invoice details for the caller to settle.
 	if statusCode == 402 {
 		payment, _ := respData["payment"].(map[string]interface{})
 		chargeID, _ := payment["charge_id"].(string)
@@ -206,14 +490,89 @@ func handleAskAI(ctx context.Context, req *mcp.CallToolRequest, input AskAIInput
 		amountSats, _ := payment["amount_sats"].(float64)
 		amountUSD, _ := payment["amount_usd"].(float64)
 
-		output := AskAIOutput{
-			Status:     "payment_required",
-			ChargeID:   chargeID,
-			PaymentReq: paymentReq,
-			AmountSats: int(amountSats),
-			AmountUSD:  amountUSD,
+		ledgerChargeID, ledgerAmountSats, ledgerAmountUSD = chargeID, int(amountSats), amountUSD
+
+		if chargeID != "" {
+			paymentStore.Upsert(payments.Entry{
+				ChargeID:   chargeID,
+				State:      payments.StateCreated,
+				Model:      model,
+				AmountSats: int(amountSats),
+				AmountUSD:  amountUSD,
+				FirstSeen:  time.Now(),
+			})
+		}
+
+		if walletBackend == nil {
+			// No spend ledger entry yet: an unpaid invoice isn't a charge.
+			// The actual spend is recorded once this chargeID is redeemed
+			// (autopay below, or a later call with payment_hash set).
+			return nil, AskAIOutput{
+				Status:     "payment_required",
+				ChargeID:   chargeID,
+				PaymentReq: paymentReq,
+				AmountSats: int(amountSats),
+				AmountUSD:  amountUSD,
+			}, nil
+		}
+
+		if chargeID != "" {
+			paymentStore.Transition(chargeID, payments.StateInFlight, "")
+		}
+
+		preimage, err := autopay(ctx, paymentReq, int(amountSats))
+		if err != nil {
+			if chargeID != "" {
+				paymentStore.Transition(chargeID, payments.StateFailed, err.Error())
+			}
+			// No spend ledger entry: autopay failed, so nothing was spent.
+			return nil, AskAIOutput{
+				Status:     "payment_required",
+				ChargeID:   chargeID,
+				PaymentReq: paymentReq,
+				AmountSats: int(amountSats),
+				AmountUSD:  amountUSD,
+				Error:      fmt.Sprintf("autopay failed: %v", err),
+			}, nil
+		}
+		if chargeID != "" {
+			paymentStore.Settle(chargeID, preimage)
+		}
+
+		// LightningProx expects the charge_id as X-Payment-Hash on the retry,
+		// not the preimage autopay just produced (see the tool's own doc
+		// string: "call again with the charge_id as payment_hash").
+		headers["X-Payment-Hash"] = chargeID
+		respBody, statusCode, err = makeRequest("POST", baseURL+endpoint, requestBody, headers)
+		if err != nil {
+			return nil, AskAIOutput{Status: "error", Error: err.Error()}, nil
+		}
+		if err := json.Unmarshal(respBody, &respData); err != nil {
+			return nil, AskAIOutput{Status: "error", Error: "failed to parse response"}, nil
+		}
+		if statusCode != 200 {
+			errMsg, _ := respData["error"].(string)
+			if errMsg == "" {
+				errMsg = fmt.Sprintf("HTTP %d: %s", statusCode, string(respBody))
+			}
+			recordSpend(chargeID, model, int(amountSats), amountUSD, input.Metadata, "error")
+			return nil, AskAIOutput{Status: "error", Error: "paid invoice but retry failed: " + errMsg}, nil
+		}
+		if chargeID != "" {
+			paymentStore.MarkConsumed(chargeID)
+		}
+	} else if statusCode == 200 && input.PaymentHash != "" {
+		// The caller paid out-of-band and is redeeming a previously created
+		// charge; reconcile the store so it reflects the successful spend.
+		// Guard against replay the same way resume_payment does: a charge
+		// whose response was already delivered once isn't re-settled.
+		ledgerChargeID = input.PaymentHash
+		if existing, ok := paymentStore.Get(input.PaymentHash); !ok || !existing.Consumed {
+			if entry, err := paymentStore.Settle(input.PaymentHash, ""); err == nil {
+				paymentStore.MarkConsumed(input.PaymentHash)
+				ledgerAmountSats, ledgerAmountUSD = entry.AmountSats, entry.AmountUSD
+			}
 		}
-		return nil, output, nil
 	}
 
 	if statusCode != 200 {
@@ -248,9 +607,417 @@ func handleAskAI(ctx context.Context, req *mcp.CallToolRequest, input AskAIInput
 		}
 	}
 
+	// The spend_token path (and any other case where LightningProx settles a
+	// charge without our ever seeing a charge_id/amount, e.g. a request that
+	// needed no payment at all) leaves the ledger amounts unset above. Back
+	// them out of the response's actual token usage when present, falling
+	// back to the same estimate get_pricing uses, so every successful call
+	// still contributes real cost-attribution data.
+	if ledgerChargeID == "" && ledgerAmountSats == 0 && ledgerAmountUSD == 0 {
+		inputTokens, outputTokens := responseUsageTokens(respData)
+		if inputTokens == 0 {
+			inputTokens = models.EstimateTokens(input.Prompt)
+		}
+		if outputTokens == 0 {
+			outputTokens = maxTokens
+		}
+		ledgerAmountSats, ledgerAmountUSD = models.EstimateCost(models.GetOrDefault(model), inputTokens, outputTokens, getBTCPriceUSD())
+	}
+
+	recordSpend(ledgerChargeID, model, ledgerAmountSats, ledgerAmountUSD, input.Metadata, "success")
 	return nil, AskAIOutput{Status: "success", Response: responseText}, nil
 }
 
+// responseUsageTokens extracts input/output token counts from an upstream
+// /v1/messages response, understanding both Anthropic's usage.input_tokens/
+// output_tokens and OpenAI's usage.prompt_tokens/completion_tokens. Returns
+// zeros if no usage block is present.
+func responseUsageTokens(respData map[string]interface{}) (int, int) {
+	usage, ok := respData["usage"].(map[string]interface{})
+	if !ok {
+		return 0, 0
+	}
+	asInt := func(keys ...string) int {
+		for _, k := range keys {
+			if v, ok := usage[k].(float64); ok {
+				return int(v)
+			}
+		}
+		return 0
+	}
+	return asInt("input_tokens", "prompt_tokens"), asInt("output_tokens", "completion_tokens")
+}
+
+// recordedCharges tracks which charge_ids already have a spend-ledger entry,
+// so a charge that's redeemed more than once (e.g. a direct ask_ai retry
+// against an already-consumed payment_hash) is only counted once. Charges
+// with no charge_id (the spend_token path) have nothing to dedupe against
+// and are always recorded, since each call there is its own distinct spend.
+var (
+	recordedChargesMu sync.Mutex
+	recordedCharges   = make(map[string]bool)
+)
+
+// seedRecordedCharges primes the dedup guard from the ledger's persisted
+// entries at startup, the same way paymentStore and spendLedger themselves
+// are loaded from disk — without this, a charge already recorded before a
+// restart would be written to the ledger again the next time it's redeemed.
+func seedRecordedCharges(entries []ledger.Entry) {
+	recordedChargesMu.Lock()
+	defer recordedChargesMu.Unlock()
+	for _, e := range entries {
+		if e.ChargeID != "" {
+			recordedCharges[e.ChargeID] = true
+		}
+	}
+}
+
+// recordSpend appends a spend-ledger entry for a resolved charge, once per
+// charge_id. It should only be called for an outcome where money actually
+// moved (a successful payment, or a paid invoice whose response retrieval
+// failed) — not for an unpaid invoice or a failed payment attempt, since
+// those haven't spent anything yet. Errors are logged rather than surfaced,
+// since the ledger is a best-effort accounting side-channel and should never
+// fail the underlying ask_ai/get_invoice call.
+func recordSpend(chargeID, model string, amountSats int, amountUSD float64, metadata map[string]string, status string) {
+	if chargeID != "" {
+		recordedChargesMu.Lock()
+		if recordedCharges[chargeID] {
+			recordedChargesMu.Unlock()
+			return
+		}
+		recordedCharges[chargeID] = true
+		recordedChargesMu.Unlock()
+	}
+
+	if err := spendLedger.Record(ledger.Entry{
+		Timestamp:  time.Now(),
+		ChargeID:   chargeID,
+		Model:      model,
+		AmountSats: amountSats,
+		AmountUSD:  amountUSD,
+		Metadata:   metadata,
+		Status:     status,
+	}); err != nil {
+		log.Printf("warning: failed to record spend ledger entry: %v", err)
+	}
+}
+
+// autopay authorizes amountSats against the budget tracker and dispatches
+// the payment to the configured wallet backend, returning the payment
+// preimage. The retried request identifies the charge by its charge_id, not
+// by this preimage.
+func autopay(ctx context.Context, paymentRequest string, amountSats int) (string, error) {
+	if err := budget.Authorize(amountSats); err != nil {
+		return "", err
+	}
+
+	payCtx, cancel := context.WithTimeout(ctx, autopayPollTimeout)
+	defer cancel()
+
+	result, err := walletBackend.Pay(payCtx, paymentRequest, defaultFeeLimitSat, defaultCLTVLimit)
+	if err != nil || result.Status != "succeeded" {
+		budget.Release(amountSats)
+		if err != nil {
+			return "", err
+		}
+		return "", fmt.Errorf("payment did not succeed (status: %s)", result.Status)
+	}
+
+	return result.Preimage, nil
+}
+
+func handleWalletInfo(ctx context.Context, req *mcp.CallToolRequest, input WalletInfoInput) (*mcp.CallToolResult, WalletInfoOutput, error) {
+	if walletBackend == nil {
+		return nil, WalletInfoOutput{Enabled: false, Status: "disabled"}, nil
+	}
+
+	info, err := walletBackend.GetInfo(ctx)
+	if err != nil {
+		return nil, WalletInfoOutput{Enabled: true, Status: "error", Error: err.Error()}, nil
+	}
+
+	return nil, WalletInfoOutput{
+		Enabled:         true,
+		Backend:         info.Backend,
+		Alias:           info.Alias,
+		BlockHeight:     info.BlockHeight,
+		SyncedToChain:   info.SyncedToChain,
+		RemainingBudget: budget.Remaining(),
+		Status:          "ok",
+	}, nil
+}
+
+func handleWalletPayInvoice(ctx context.Context, req *mcp.CallToolRequest, input WalletPayInvoiceInput) (*mcp.CallToolResult, WalletPayInvoiceOutput, error) {
+	if walletBackend == nil {
+		return nil, WalletPayInvoiceOutput{Status: "error", Error: "autopay is disabled (LIGHTNING_BACKEND=none)"}, nil
+	}
+
+	if err := budget.Authorize(input.AmountSats); err != nil {
+		return nil, WalletPayInvoiceOutput{Status: "error", Error: err.Error()}, nil
+	}
+
+	feeLimitSat := input.FeeLimitSat
+	if feeLimitSat <= 0 {
+		feeLimitSat = defaultFeeLimitSat
+	}
+	cltvLimit := input.CLTVLimit
+	if cltvLimit <= 0 {
+		cltvLimit = defaultCLTVLimit
+	}
+
+	payCtx, cancel := context.WithTimeout(ctx, autopayPollTimeout)
+	defer cancel()
+
+	result, err := walletBackend.Pay(payCtx, input.PaymentRequest, feeLimitSat, cltvLimit)
+	if err != nil {
+		budget.Release(input.AmountSats)
+		return nil, WalletPayInvoiceOutput{Status: "error", Error: err.Error()}, nil
+	}
+	if result.Status != "succeeded" {
+		budget.Release(input.AmountSats)
+	}
+
+	return nil, WalletPayInvoiceOutput{
+		Preimage: result.Preimage,
+		FeeSat:   result.FeeSat,
+		Status:   result.Status,
+	}, nil
+}
+
+func handleWalletSetBudget(ctx context.Context, req *mcp.CallToolRequest, input WalletSetBudgetInput) (*mcp.CallToolResult, WalletSetBudgetOutput, error) {
+	// Update the ceilings in place rather than swapping in a fresh tracker,
+	// so a caller can't reset spentToday (and so DAILY_SATS_BUDGET) by
+	// re-issuing the same or any limits.
+	budget.SetCeilings(input.MaxSatsPerCall, input.DailySatsBudget)
+	return nil, WalletSetBudgetOutput{
+		MaxSatsPerCall:  input.MaxSatsPerCall,
+		DailySatsBudget: input.DailySatsBudget,
+		Status:          "updated",
+	}, nil
+}
+
+func handleTrackPayment(ctx context.Context, req *mcp.CallToolRequest, input TrackPaymentInput) (*mcp.CallToolResult, TrackPaymentOutput, error) {
+	progressToken := req.Params.GetProgressToken()
+
+	entry, ok := paymentStore.Get(input.ChargeID)
+	if !ok {
+		return nil, TrackPaymentOutput{ChargeID: input.ChargeID, Status: "error", Error: fmt.Sprintf("unknown charge_id %q", input.ChargeID)}, nil
+	}
+
+	var progress float64
+	lastState := payments.State("")
+	for {
+		if entry.State != lastState {
+			lastState = entry.State
+			progress++
+			if progressToken != nil {
+				req.Session.NotifyProgress(ctx, &mcp.ProgressNotificationParams{
+					ProgressToken: progressToken,
+					Message:       fmt.Sprintf("charge %s: %s", input.ChargeID, entry.State),
+					Progress:      progress,
+				})
+			}
+		}
+
+		if entry.State.Terminal() {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, TrackPaymentOutput{
+				ChargeID: input.ChargeID,
+				State:    string(entry.State),
+				Attempts: entry.Attempts,
+				Status:   "timeout",
+			}, nil
+		case <-time.After(progressPollInterval):
+		}
+
+		entry, ok = paymentStore.Get(input.ChargeID)
+		if !ok {
+			return nil, TrackPaymentOutput{ChargeID: input.ChargeID, Status: "error", Error: "charge disappeared from the store"}, nil
+		}
+	}
+
+	return nil, TrackPaymentOutput{
+		ChargeID: input.ChargeID,
+		State:    string(entry.State),
+		Attempts: entry.Attempts,
+		LastErr:  entry.LastErr,
+		Status:   "ok",
+	}, nil
+}
+
+func handleListPayments(ctx context.Context, req *mcp.CallToolRequest, input ListPaymentsInput) (*mcp.CallToolResult, ListPaymentsOutput, error) {
+	var out []PaymentSummary
+	for _, e := range paymentStore.List() {
+		if input.State != "" && string(e.State) != input.State {
+			continue
+		}
+		summary := PaymentSummary{
+			ChargeID:   e.ChargeID,
+			State:      string(e.State),
+			Model:      e.Model,
+			AmountSats: e.AmountSats,
+			AmountUSD:  e.AmountUSD,
+			Attempts:   e.Attempts,
+			Consumed:   e.Consumed,
+			FirstSeen:  e.FirstSeen.Format(time.RFC3339),
+		}
+		if e.SettledAt != nil {
+			summary.SettledAt = e.SettledAt.Format(time.RFC3339)
+		}
+		out = append(out, summary)
+	}
+	return nil, ListPaymentsOutput{Payments: out}, nil
+}
+
+func handleResumePayment(ctx context.Context, req *mcp.CallToolRequest, input ResumePaymentInput) (*mcp.CallToolResult, AskAIOutput, error) {
+	entry, ok := paymentStore.Get(input.ChargeID)
+	if !ok {
+		return nil, AskAIOutput{Status: "error", Error: fmt.Sprintf("unknown charge_id %q", input.ChargeID)}, nil
+	}
+	if entry.State != payments.StateSucceeded {
+		return nil, AskAIOutput{Status: "error", Error: fmt.Sprintf("charge_id %q is not settled (state: %s)", input.ChargeID, entry.State)}, nil
+	}
+	if entry.Consumed {
+		return nil, AskAIOutput{Status: "error", Error: fmt.Sprintf("charge_id %q was already consumed", input.ChargeID)}, nil
+	}
+	if entry.Model != input.Model {
+		return nil, AskAIOutput{Status: "error", Error: fmt.Sprintf("model %q does not match the model %q the charge_id %q was created for", input.Model, entry.Model, input.ChargeID)}, nil
+	}
+
+	return handleAskAI(ctx, req, AskAIInput{
+		Model:       input.Model,
+		Prompt:      input.Prompt,
+		MaxTokens:   input.MaxTokens,
+		PaymentHash: input.ChargeID,
+	})
+}
+
+func handleGetSwapQuote(ctx context.Context, req *mcp.CallToolRequest, input GetSwapQuoteInput) (*mcp.CallToolResult, GetSwapQuoteOutput, error) {
+	if swapProvider == nil {
+		return nil, GetSwapQuoteOutput{Status: "error", Error: "swap fallback is disabled (SWAP_PROVIDER_URL is unset)"}, nil
+	}
+
+	quote, err := swapProvider.GetQuote(ctx, input.AmountSats)
+	if err != nil {
+		return nil, GetSwapQuoteOutput{Status: "error", Error: err.Error()}, nil
+	}
+
+	return nil, GetSwapQuoteOutput{
+		AmountSats:       quote.AmountSat,
+		RoutingBufferSat: quote.RoutingBufferSat,
+		EstimatedFeeSat:  quote.EstimatedFeeSat,
+		TotalSat:         quote.TotalSat,
+		Status:           "ok",
+	}, nil
+}
+
+func handleSubmitSwap(ctx context.Context, req *mcp.CallToolRequest, input SubmitSwapInput) (*mcp.CallToolResult, SubmitSwapOutput, error) {
+	if swapProvider == nil {
+		return nil, SubmitSwapOutput{Status: "error", Error: "swap fallback is disabled (SWAP_PROVIDER_URL is unset)"}, nil
+	}
+
+	result, err := swapProvider.SubmitSwap(ctx, input.PaymentRequest, input.AmountSats)
+	if err != nil {
+		return nil, SubmitSwapOutput{Status: "error", Error: err.Error()}, nil
+	}
+
+	return nil, SubmitSwapOutput{
+		SwapID:         result.SwapID,
+		Address:        result.Address,
+		ExpectedFeeSat: result.ExpectedFeeSat,
+		ExpiresAt:      result.ExpiresAt.Format(time.RFC3339),
+		Status:         "awaiting_onchain_payment",
+	}, nil
+}
+
+func handleCheckSwapStatus(ctx context.Context, req *mcp.CallToolRequest, input CheckSwapStatusInput) (*mcp.CallToolResult, CheckSwapStatusOutput, error) {
+	if swapProvider == nil {
+		return nil, CheckSwapStatusOutput{Status: "error", Error: "swap fallback is disabled (SWAP_PROVIDER_URL is unset)"}, nil
+	}
+
+	status, err := swapProvider.CheckStatus(ctx, input.SwapID)
+	if err != nil {
+		return nil, CheckSwapStatusOutput{SwapID: input.SwapID, Status: "error", Error: err.Error()}, nil
+	}
+
+	// Propagate the swap's terminal states into the payment store so
+	// resume_payment (which requires StateSucceeded) actually works once the
+	// swap pays the underlying invoice; skip once the charge is already
+	// terminal so repeated polling doesn't keep bumping its Attempts.
+	if chargeID, ok := swapCharges.ChargeID(input.SwapID); ok {
+		if entry, found := paymentStore.Get(chargeID); found && !entry.State.Terminal() {
+			switch status.State {
+			case "paid":
+				paymentStore.Settle(chargeID, status.Preimage)
+			case "failed":
+				paymentStore.Transition(chargeID, payments.StateFailed, "swap failed")
+			case "expired":
+				paymentStore.Transition(chargeID, payments.StateExpired, "swap expired")
+			}
+		}
+	}
+
+	return nil, CheckSwapStatusOutput{
+		SwapID:   status.SwapID,
+		State:    status.State,
+		Preimage: status.Preimage,
+		Status:   "ok",
+	}, nil
+}
+
+func handlePayViaSwap(ctx context.Context, req *mcp.CallToolRequest, input PayViaSwapInput) (*mcp.CallToolResult, PayViaSwapOutput, error) {
+	if swapProvider == nil {
+		return nil, PayViaSwapOutput{Status: "error", Error: "swap fallback is disabled (SWAP_PROVIDER_URL is unset)"}, nil
+	}
+
+	_, invoice, err := handleGetInvoice(ctx, req, GetInvoiceInput{Model: input.Model, Prompt: input.Prompt, MaxTokens: input.MaxTokens})
+	if err != nil {
+		return nil, PayViaSwapOutput{Status: "error", Error: err.Error()}, nil
+	}
+	if invoice.Status != "invoice_generated" {
+		return nil, PayViaSwapOutput{Status: "error", Error: fmt.Sprintf("failed to obtain invoice: %s", invoice.Status)}, nil
+	}
+
+	result, err := swapProvider.SubmitSwap(ctx, invoice.PaymentReq, invoice.AmountSats)
+	if err != nil {
+		return nil, PayViaSwapOutput{
+			ChargeID:   invoice.ChargeID,
+			AmountSats: invoice.AmountSats,
+			AmountUSD:  invoice.AmountUSD,
+			Status:     "error",
+			Error:      fmt.Sprintf("failed to submit swap: %v", err),
+		}, nil
+	}
+
+	paymentStore.Upsert(payments.Entry{
+		ChargeID:   invoice.ChargeID,
+		State:      payments.StateCreated,
+		Model:      input.Model,
+		AmountSats: invoice.AmountSats,
+		AmountUSD:  invoice.AmountUSD,
+		FirstSeen:  time.Now(),
+	})
+	if err := swapCharges.Track(result.SwapID, invoice.ChargeID); err != nil {
+		log.Printf("warning: could not persist swap_id -> charge_id mapping: %v", err)
+	}
+
+	return nil, PayViaSwapOutput{
+		ChargeID:       invoice.ChargeID,
+		SwapID:         result.SwapID,
+		Address:        result.Address,
+		ExpectedFeeSat: result.ExpectedFeeSat,
+		AmountSats:     invoice.AmountSats,
+		AmountUSD:      invoice.AmountUSD,
+		ExpiresAt:      result.ExpiresAt.Format(time.RFC3339),
+		Status:         "awaiting_onchain_payment",
+	}, nil
+}
+
 func handleGetInvoice(ctx context.Context, req *mcp.CallToolRequest, input GetInvoiceInput) (*mcp.CallToolResult, GetInvoiceOutput, error) {
 	baseURL := getLightningProxURL()
 
@@ -277,7 +1044,14 @@ func handleGetInvoice(ctx context.Context, req *mcp.CallToolRequest, input GetIn
 
 	endpoint := "/v1/messages"
 
-	respBody, statusCode, err := makeRequest("POST", baseURL+endpoint, requestBody, nil)
+	headers := make(map[string]string)
+	if len(input.Metadata) > 0 {
+		if metaJSON, err := json.Marshal(input.Metadata); err == nil {
+			headers["X-Metadata"] = string(metaJSON)
+		}
+	}
+
+	respBody, statusCode, err := makeRequest("POST", baseURL+endpoint, requestBody, headers)
 	if err != nil {
 		return nil, GetInvoiceOutput{Status: "error"}, nil
 	}
@@ -297,6 +1071,21 @@ func handleGetInvoice(ctx context.Context, req *mcp.CallToolRequest, input GetIn
 	amountSats, _ := payment["amount_sats"].(float64)
 	amountUSD, _ := payment["amount_usd"].(float64)
 
+	// No spend ledger entry here: an invoice is not yet a charge. ask_ai
+	// records the actual spend once this charge_id is redeemed.
+	// Track it in the payment store so track_payment and a later ask_ai
+	// redemption can find it.
+	if chargeID != "" {
+		paymentStore.Upsert(payments.Entry{
+			ChargeID:   chargeID,
+			State:      payments.StateCreated,
+			Model:      model,
+			AmountSats: int(amountSats),
+			AmountUSD:  amountUSD,
+			FirstSeen:  time.Now(),
+		})
+	}
+
 	return nil, GetInvoiceOutput{
 		ChargeID:   chargeID,
 		PaymentReq: paymentReq,
@@ -343,90 +1132,289 @@ func handleCheckBalance(ctx context.Context, req *mcp.CallToolRequest, input Che
 }
 
 func handleListModels(ctx context.Context, req *mcp.CallToolRequest, input ListModelsInput) (*mcp.CallToolResult, ListModelsOutput, error) {
-	// Return the models LightningProx currently supports
-	// These match isValidModel() on the backend
-	models := []ModelInfo{
-		{
-			ID:         "claude-sonnet-4-20250514",
-			Provider:   "anthropic",
-			InputCost:  0.003,
-			OutputCost: 0.015,
-			MaxContext: 200000,
-		},
-		{
-			ID:         "claude-3-5-sonnet-20241022",
-			Provider:   "anthropic",
-			InputCost:  0.003,
-			OutputCost: 0.015,
-			MaxContext: 200000,
-		},
-		{
-			ID:         "gpt-4-turbo",
-			Provider:   "openai",
-			InputCost:  0.01,
-			OutputCost: 0.03,
-			MaxContext: 128000,
-		},
-		{
-			ID:         "gpt-3.5-turbo",
-			Provider:   "openai",
-			InputCost:  0.0005,
-			OutputCost: 0.0015,
-			MaxContext: 16385,
-		},
+	var out []ModelInfo
+	for _, m := range models.All() {
+		out = append(out, ModelInfo{
+			ID:           m.ID,
+			Provider:     m.Provider,
+			InputCost:    m.InputCostPer1K,
+			OutputCost:   m.OutputCostPer1K,
+			MaxContext:   m.MaxContext,
+			QualityScore: m.QualityScore,
+		})
 	}
 
-	return nil, ListModelsOutput{Models: models}, nil
+	return nil, ListModelsOutput{Models: out}, nil
+}
+
+// getBTCPriceUSD returns BTC_PRICE_USD if set, otherwise a rough default.
+func getBTCPriceUSD() float64 {
+	btcPrice := 100000.0 // rough: 1 BTC = ~$100,000 as of early 2026
+	if envPrice := os.Getenv("BTC_PRICE_USD"); envPrice != "" {
+		fmt.Sscanf(envPrice, "%f", &btcPrice)
+	}
+	return btcPrice
 }
 
 func handleGetPricing(ctx context.Context, req *mcp.CallToolRequest, input GetPricingInput) (*mcp.CallToolResult, GetPricingOutput, error) {
-	model := input.Model
-	if model == "" {
-		model = "claude-sonnet-4-20250514"
+	maxTokens := input.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = 1024
 	}
+
+	m := models.GetOrDefault(input.Model)
+
+	// Estimate: assume ~100 input tokens (short prompt) + maxTokens output
+	estimatedSats, estimatedUSD := models.EstimateCost(m, 100, maxTokens, getBTCPriceUSD())
+
+	return nil, GetPricingOutput{
+		Model:           m.ID,
+		EstimatedSats:   estimatedSats,
+		EstimatedUSD:    estimatedUSD,
+		InputCostPer1K:  m.PricedInputCostPer1K(),
+		OutputCostPer1K: m.PricedOutputCostPer1K(),
+		Markup:          "20%",
+	}, nil
+}
+
+// qualityThreshold maps a quality_tier name to a minimum QualityScore.
+func qualityThreshold(tier string) float64 {
+	switch tier {
+	case "premium":
+		return 0.9
+	case "balanced":
+		return 0.7
+	default: // "economy" or unset
+		return 0
+	}
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func handleSuggestModel(ctx context.Context, req *mcp.CallToolRequest, input SuggestModelInput) (*mcp.CallToolResult, SuggestModelOutput, error) {
 	maxTokens := input.MaxTokens
 	if maxTokens <= 0 {
 		maxTokens = 1024
 	}
+	promptTokens := models.EstimateTokens(input.Prompt)
+	minQuality := qualityThreshold(input.QualityTier)
+	btcPrice := getBTCPriceUSD()
+
+	var candidates []ModelCandidate
+	for _, m := range models.All() {
+		if len(input.PreferredProviders) > 0 && !containsFold(input.PreferredProviders, m.Provider) {
+			continue
+		}
+		if containsFold(input.ExcludedProviders, m.Provider) {
+			continue
+		}
+		if m.QualityScore < minQuality {
+			continue
+		}
+		if promptTokens+maxTokens > m.MaxContext {
+			continue
+		}
+
+		sats, usd := models.EstimateCost(m, promptTokens, maxTokens, btcPrice)
+		if input.MaxSats > 0 && sats > input.MaxSats {
+			continue
+		}
+		if input.MaxUSD > 0 && usd > input.MaxUSD {
+			continue
+		}
 
-	// Pricing table (including 20% LightningProx markup)
-	type pricing struct {
-		inputCost  float64
-		outputCost float64
+		candidates = append(candidates, ModelCandidate{
+			Model:         m.ID,
+			Provider:      m.Provider,
+			EstimatedSats: sats,
+			EstimatedUSD:  usd,
+			QualityScore:  m.QualityScore,
+			Reason: fmt.Sprintf("fits %d-token context window, estimated %d sats at quality score %.2f",
+				m.MaxContext, sats, m.QualityScore),
+		})
 	}
-	prices := map[string]pricing{
-		"claude-sonnet-4-20250514":   {0.003 * 1.2, 0.015 * 1.2},
-		"claude-3-5-sonnet-20241022": {0.003 * 1.2, 0.015 * 1.2},
-		"gpt-4-turbo":               {0.01 * 1.2, 0.03 * 1.2},
-		"gpt-3.5-turbo":             {0.0005 * 1.2, 0.0015 * 1.2},
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].EstimatedSats != candidates[j].EstimatedSats {
+			return candidates[i].EstimatedSats < candidates[j].EstimatedSats
+		}
+		return candidates[i].QualityScore > candidates[j].QualityScore
+	})
+
+	return nil, SuggestModelOutput{Candidates: candidates}, nil
+}
+
+func handleAskAIAuto(ctx context.Context, req *mcp.CallToolRequest, input AskAIAutoInput) (*mcp.CallToolResult, AskAIOutput, error) {
+	_, suggestion, err := handleSuggestModel(ctx, req, SuggestModelInput{
+		Prompt:             input.Prompt,
+		MaxTokens:          input.MaxTokens,
+		QualityTier:        input.QualityTier,
+		MaxSats:            input.MaxSats,
+		MaxUSD:             input.MaxUSD,
+		PreferredProviders: input.PreferredProviders,
+		ExcludedProviders:  input.ExcludedProviders,
+	})
+	if err != nil {
+		return nil, AskAIOutput{Status: "error", Error: err.Error()}, nil
+	}
+	if len(suggestion.Candidates) == 0 {
+		return nil, AskAIOutput{Status: "error", Error: "no model satisfies the given constraints"}, nil
 	}
 
-	p, ok := prices[model]
-	if !ok {
-		p = pricing{0.003 * 1.2, 0.015 * 1.2} // default to sonnet pricing
+	var lastErr string
+	for _, candidate := range suggestion.Candidates {
+		_, out, err := handleAskAI(ctx, req, AskAIInput{Model: candidate.Model, Prompt: input.Prompt, MaxTokens: input.MaxTokens})
+		if err != nil {
+			lastErr = err.Error()
+			continue
+		}
+		if out.Status == "error" {
+			lastErr = out.Error
+			continue
+		}
+		out.ModelUsed = candidate.Model
+		return nil, out, nil
 	}
 
-	// Estimate: assume ~100 input tokens (short prompt) + maxTokens output
-	estimatedUSD := (100.0/1000.0)*p.inputCost + (float64(maxTokens)/1000.0)*p.outputCost
+	return nil, AskAIOutput{Status: "error", Error: fmt.Sprintf("all %d candidate models failed, last error: %s", len(suggestion.Candidates), lastErr)}, nil
+}
 
-	// Convert to sats (rough: 1 BTC = ~$100,000 as of early 2026)
-	btcPrice := 100000.0
-	if envPrice := os.Getenv("BTC_PRICE_USD"); envPrice != "" {
-		fmt.Sscanf(envPrice, "%f", &btcPrice)
+// parseOptionalRFC3339 parses s as RFC3339 if non-empty, returning the zero
+// time (which filterLedgerEntries treats as "no bound") otherwise.
+func parseOptionalRFC3339(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
 	}
-	estimatedSats := int((estimatedUSD / btcPrice) * 100_000_000)
-	if estimatedSats < 1 {
-		estimatedSats = 1
+	return time.Parse(time.RFC3339, s)
+}
+
+// filterLedgerEntries returns the entries matching every supplied filter.
+// Zero values (since/until) or empty strings (model, status, metaKey) mean
+// "no restriction" for that filter.
+func filterLedgerEntries(entries []ledger.Entry, since, until time.Time, model, status, metaKey, metaValue string) []ledger.Entry {
+	var out []ledger.Entry
+	for _, e := range entries {
+		if !since.IsZero() && e.Timestamp.Before(since) {
+			continue
+		}
+		if !until.IsZero() && !e.Timestamp.Before(until) {
+			continue
+		}
+		if model != "" && e.Model != model {
+			continue
+		}
+		if status != "" && e.Status != status {
+			continue
+		}
+		if metaKey != "" {
+			v, ok := e.Metadata[metaKey]
+			if !ok || (metaValue != "" && v != metaValue) {
+				continue
+			}
+		}
+		out = append(out, e)
 	}
+	return out
+}
 
-	return nil, GetPricingOutput{
-		Model:           model,
-		EstimatedSats:   estimatedSats,
-		EstimatedUSD:    estimatedUSD,
-		InputCostPer1K:  p.inputCost,
-		OutputCostPer1K: p.outputCost,
-		Markup:          "20%",
-	}, nil
+// spendGroupKey extracts the group_by bucket key for a ledger entry: "model"
+// and "status" read the matching field directly, and "meta:<key>" reads
+// Metadata[<key>].
+func spendGroupKey(e ledger.Entry, groupBy string) string {
+	switch {
+	case groupBy == "model":
+		return e.Model
+	case groupBy == "status":
+		return e.Status
+	case strings.HasPrefix(groupBy, "meta:"):
+		return e.Metadata[strings.TrimPrefix(groupBy, "meta:")]
+	default:
+		return ""
+	}
+}
+
+func handleQuerySpend(ctx context.Context, req *mcp.CallToolRequest, input QuerySpendInput) (*mcp.CallToolResult, QuerySpendOutput, error) {
+	since, err := parseOptionalRFC3339(input.Since)
+	if err != nil {
+		return nil, QuerySpendOutput{Status: "error", Error: fmt.Sprintf("invalid since: %v", err)}, nil
+	}
+	until, err := parseOptionalRFC3339(input.Until)
+	if err != nil {
+		return nil, QuerySpendOutput{Status: "error", Error: fmt.Sprintf("invalid until: %v", err)}, nil
+	}
+
+	entries := filterLedgerEntries(spendLedger.List(), since, until, input.Model, input.Status, input.MetadataKey, input.MetadataValue)
+
+	out := QuerySpendOutput{Status: "ok"}
+	groups := make(map[string]*SpendGroup)
+	var order []string
+	for _, e := range entries {
+		out.Count++
+		out.TotalSats += e.AmountSats
+		out.TotalUSD += e.AmountUSD
+
+		if input.GroupBy == "" {
+			continue
+		}
+		key := spendGroupKey(e, input.GroupBy)
+		g, ok := groups[key]
+		if !ok {
+			g = &SpendGroup{Key: key}
+			groups[key] = g
+			order = append(order, key)
+		}
+		g.Count++
+		g.TotalSats += e.AmountSats
+		g.TotalUSD += e.AmountUSD
+	}
+	for _, k := range order {
+		out.Groups = append(out.Groups, *groups[k])
+	}
+	sort.SliceStable(out.Groups, func(i, j int) bool { return out.Groups[i].TotalSats > out.Groups[j].TotalSats })
+
+	return nil, out, nil
+}
+
+func handleExportSpendCSV(ctx context.Context, req *mcp.CallToolRequest, input ExportSpendCSVInput) (*mcp.CallToolResult, ExportSpendCSVOutput, error) {
+	since, err := parseOptionalRFC3339(input.Since)
+	if err != nil {
+		return nil, ExportSpendCSVOutput{Status: "error", Error: fmt.Sprintf("invalid since: %v", err)}, nil
+	}
+	until, err := parseOptionalRFC3339(input.Until)
+	if err != nil {
+		return nil, ExportSpendCSVOutput{Status: "error", Error: fmt.Sprintf("invalid until: %v", err)}, nil
+	}
+
+	entries := filterLedgerEntries(spendLedger.List(), since, until, input.Model, "", "", "")
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	_ = w.Write([]string{"ts", "charge_id", "model", "sats", "usd", "status", "metadata"})
+	for _, e := range entries {
+		metaJSON, _ := json.Marshal(e.Metadata)
+		_ = w.Write([]string{
+			e.Timestamp.Format(time.RFC3339),
+			e.ChargeID,
+			e.Model,
+			fmt.Sprintf("%d", e.AmountSats),
+			fmt.Sprintf("%.6f", e.AmountUSD),
+			e.Status,
+			string(metaJSON),
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, ExportSpendCSVOutput{Status: "error", Error: err.Error()}, nil
+	}
+
+	return nil, ExportSpendCSVOutput{CSV: buf.String(), Count: len(entries), Status: "ok"}, nil
 }
 
 // --- Main ---
@@ -435,6 +1423,59 @@ func main() {
 	log.SetOutput(os.Stderr) // MCP uses stdout for protocol messages
 	log.Println("Starting LightningProx MCP Server v" + ServerVersion)
 
+	walletCfg := wallet.ConfigFromEnv()
+	budget = wallet.NewBudgetTracker(walletCfg.MaxSatsPerCall, walletCfg.DailySatsBudget)
+	backend, err := wallet.NewBackend(walletCfg)
+	if err != nil {
+		log.Fatalf("wallet configuration error: %v", err)
+	}
+	walletBackend = backend
+	if walletBackend != nil {
+		log.Printf("Autopay enabled via %s backend", walletBackend.Name())
+	}
+
+	storePath, err := payments.DefaultPath()
+	if err != nil {
+		log.Printf("warning: could not resolve payment store path (%v), tracking in-memory only", err)
+		storePath = ""
+	}
+	paymentStore, err = payments.Open(storePath)
+	if err != nil {
+		log.Printf("warning: could not load payment store (%v), starting fresh in-memory", err)
+		paymentStore, _ = payments.Open("")
+	}
+
+	swapProvider, err = swap.NewProvider(swap.ConfigFromEnv())
+	if err != nil {
+		log.Fatalf("swap configuration error: %v", err)
+	}
+	if swapProvider != nil {
+		log.Printf("Swap fallback enabled via %s provider", swapProvider.Name())
+	}
+
+	ledgerPath, err := ledger.DefaultPath()
+	if err != nil {
+		log.Printf("warning: could not resolve spend ledger path (%v), tracking in-memory only", err)
+		ledgerPath = ""
+	}
+	spendLedger, err = ledger.Open(ledgerPath)
+	if err != nil {
+		log.Printf("warning: could not load spend ledger (%v), starting fresh in-memory", err)
+		spendLedger, _ = ledger.Open("")
+	}
+	seedRecordedCharges(spendLedger.List())
+
+	swapChargesPath, err := swapstate.DefaultPath()
+	if err != nil {
+		log.Printf("warning: could not resolve swap charge map path (%v), tracking in-memory only", err)
+		swapChargesPath = ""
+	}
+	swapCharges, err = swapstate.Open(swapChargesPath)
+	if err != nil {
+		log.Printf("warning: could not load swap charge map (%v), starting fresh in-memory", err)
+		swapCharges, _ = swapstate.Open("")
+	}
+
 	server := mcp.NewServer(
 		&mcp.Implementation{
 			Name:    ServerName,
@@ -451,6 +1492,9 @@ func main() {
 If no payment_hash or spend_token is provided, returns a Lightning invoice that must be paid first.
 After paying, call again with the charge_id as payment_hash to get the AI response.
 If using a prepaid spend token, include it and the request is processed immediately.
+If LIGHTNING_BACKEND is configured, the invoice is paid automatically from the
+server's own wallet (subject to MAX_SATS_PER_CALL/DAILY_SATS_BUDGET) and the
+response is returned in this same call.
 
 Supports Anthropic models (claude-*) and OpenAI models (gpt-*, o1-*).`,
 	}, handleAskAI)
@@ -483,6 +1527,122 @@ Returns balance in sats, estimated USD value, approximate requests remaining, an
 Useful for budget-conscious agents to compare costs before choosing a model.`,
 	}, handleGetPricing)
 
+	// Tool: wallet_info — Report the configured autopay wallet's status
+	mcp.AddTool(server, &mcp.Tool{
+		Name: "wallet_info",
+		Description: `Report whether autopay is enabled and, if so, the status of the
+configured Lightning wallet (lnd or cln) plus the remaining daily spend budget.`,
+	}, handleWalletInfo)
+
+	// Tool: wallet_pay_invoice — Pay an arbitrary invoice from the autopay wallet
+	mcp.AddTool(server, &mcp.Tool{
+		Name: "wallet_pay_invoice",
+		Description: `Pay a BOLT11 invoice directly from the configured autopay wallet.
+Requires LIGHTNING_BACKEND=lnd or cln. Subject to MAX_SATS_PER_CALL and
+DAILY_SATS_BUDGET. ask_ai uses this internally when autopay is enabled;
+call it directly to pay invoices obtained from get_invoice or elsewhere.`,
+	}, handleWalletPayInvoice)
+
+	// Tool: wallet_set_budget — Override the in-process autopay spend ceilings
+	mcp.AddTool(server, &mcp.Tool{
+		Name: "wallet_set_budget",
+		Description: `Override the MAX_SATS_PER_CALL and DAILY_SATS_BUDGET ceilings for the
+remainder of this server's process lifetime. Does not persist across restarts.`,
+	}, handleWalletSetBudget)
+
+	// Tool: track_payment — Follow a charge through its lifecycle
+	mcp.AddTool(server, &mcp.Tool{
+		Name: "track_payment",
+		Description: `Track a charge_id (from ask_ai or get_invoice) through its payment lifecycle:
+created -> in_flight -> succeeded/failed/expired. If the caller's request includes
+a progress token, this emits an MCP progress notification on every state change;
+it also returns the final state directly once terminal or once the call times out.`,
+	}, handleTrackPayment)
+
+	// Tool: list_payments — List tracked charges
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "list_payments",
+		Description: `List tracked charges, including those from before a restart (state is persisted). Optionally filter by state.`,
+	}, handleListPayments)
+
+	// Tool: resume_payment — Redeem a settled charge that was never consumed
+	mcp.AddTool(server, &mcp.Tool{
+		Name: "resume_payment",
+		Description: `Re-invoke ask_ai for a charge_id that was settled (paid) but whose AI
+response was never retrieved, e.g. because the server crashed between payment and
+response. model and prompt must match the original request.`,
+	}, handleResumePayment)
+
+	// Tool: get_swap_quote — Estimate the cost of an on-chain-to-Lightning swap
+	mcp.AddTool(server, &mcp.Tool{
+		Name: "get_swap_quote",
+		Description: `Estimate the on-chain miner fee and routing buffer for swapping BTC into a
+Lightning payment of amount_sats via the configured swap provider (Boltz-compatible
+or loopd). Does not create a swap; use submit_swap for that.`,
+	}, handleGetSwapQuote)
+
+	// Tool: submit_swap — Create a loop-in swap for an invoice
+	mcp.AddTool(server, &mcp.Tool{
+		Name: "submit_swap",
+		Description: `Create a submarine swap that will pay payment_request once its returned
+on-chain address receives and confirms amount_sats (plus fees). Use check_swap_status
+to follow it to completion.`,
+	}, handleSubmitSwap)
+
+	// Tool: check_swap_status — Poll a previously submitted swap
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "check_swap_status",
+		Description: `Check the status of a swap_id from submit_swap or pay_via_swap: pending, mempool, confirmed, paid, failed, or expired.`,
+	}, handleCheckSwapStatus)
+
+	// Tool: pay_via_swap — Buy AI inference using only on-chain BTC
+	mcp.AddTool(server, &mcp.Tool{
+		Name: "pay_via_swap",
+		Description: `For agents with only on-chain BTC: get a LightningProx invoice for model/prompt,
+submit it to the configured swap provider, and return the on-chain address to fund.
+Once that address confirms, the provider pays the invoice and the AI response becomes
+available via a retried ask_ai call with the returned charge_id, or via resume_payment
+once check_swap_status has reported the swap as paid.`,
+	}, handlePayViaSwap)
+
+	// Tool: suggest_model — Rank candidate models by cost, context fit, and quality
+	mcp.AddTool(server, &mcp.Tool{
+		Name: "suggest_model",
+		Description: `Given a prompt and constraints (max_tokens, quality_tier, max_sats, max_usd,
+preferred_providers, excluded_providers), return a ranked list of candidate models
+with estimated cost and a reason for each. Filters out models whose context window
+can't fit the prompt, and any over budget or below the requested quality_tier.
+Does not dispatch anything — use ask_ai or ask_ai_auto with the chosen model.`,
+	}, handleSuggestModel)
+
+	// Tool: ask_ai_auto — suggest_model + ask_ai in one call
+	mcp.AddTool(server, &mcp.Tool{
+		Name: "ask_ai_auto",
+		Description: `Like ask_ai, but picks the model automatically: calls suggest_model with the
+given constraints and dispatches to the top candidate, falling back to the next
+candidate if the upstream call errors. Returns the same shape as ask_ai, plus
+model_used indicating which model was actually dispatched to.`,
+	}, handleAskAIAuto)
+
+	// Tool: query_spend — Filter and aggregate the local spend ledger
+	mcp.AddTool(server, &mcp.Tool{
+		Name: "query_spend",
+		Description: `Filter the local spend ledger (one entry per charge that was actually paid,
+recorded by ask_ai once a charge settles) by time range, model, status, or a
+metadata key/value, and return aggregate totals (count, total_sats, total_usd).
+Set group_by to "model", "status", or "meta:<key>" (e.g. meta:tenant) to also
+break the totals down into groups. Useful for multi-tenant or multi-agent cost
+attribution.`,
+	}, handleQuerySpend)
+
+	// Tool: export_spend_csv — Export the local spend ledger as CSV
+	mcp.AddTool(server, &mcp.Tool{
+		Name: "export_spend_csv",
+		Description: `Export the local spend ledger, optionally filtered by time range or model,
+as CSV text (columns: ts, charge_id, model, sats, usd, status, metadata) for
+import into accounting or BI tools.`,
+	}, handleExportSpendCSV)
+
 	// Run on stdio transport (standard for Claude Desktop, Cursor, etc.)
 	log.Println("MCP server ready, listening on stdio")
 	if err := server.Run(context.Background(), &mcp.StdioTransport{}); err != nil {
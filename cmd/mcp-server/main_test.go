@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestQualityThreshold(t *testing.T) {
+	tests := []struct {
+		tier string
+		want float64
+	}{
+		{"premium", 0.9},
+		{"balanced", 0.7},
+		{"economy", 0},
+		{"", 0},
+		{"nonsense", 0},
+	}
+	for _, tt := range tests {
+		if got := qualityThreshold(tt.tier); got != tt.want {
+			t.Errorf("qualityThreshold(%q) = %v, want %v", tt.tier, got, tt.want)
+		}
+	}
+}
+
+func TestHandleSuggestModelFiltersByContextWindow(t *testing.T) {
+	// gpt-3.5-turbo's 16385-token context can't fit a prompt this large;
+	// the rest of the registry can.
+	prompt := make([]byte, 70000)
+	for i := range prompt {
+		prompt[i] = 'a'
+	}
+
+	_, out, err := handleSuggestModel(context.Background(), nil, SuggestModelInput{
+		Prompt: string(prompt),
+	})
+	if err != nil {
+		t.Fatalf("handleSuggestModel: %v", err)
+	}
+	for _, c := range out.Candidates {
+		if c.Model == "gpt-3.5-turbo" {
+			t.Fatalf("expected gpt-3.5-turbo to be excluded for not fitting the context window, got %+v", out.Candidates)
+		}
+	}
+	if len(out.Candidates) == 0 {
+		t.Fatal("expected at least one candidate to fit a 70000-byte prompt")
+	}
+}
+
+func TestHandleSuggestModelFiltersByQualityTier(t *testing.T) {
+	_, out, err := handleSuggestModel(context.Background(), nil, SuggestModelInput{
+		Prompt:      "hello",
+		QualityTier: "premium",
+	})
+	if err != nil {
+		t.Fatalf("handleSuggestModel: %v", err)
+	}
+	for _, c := range out.Candidates {
+		if c.QualityScore < 0.9 {
+			t.Fatalf("expected only premium-tier candidates, got %+v", c)
+		}
+	}
+}
+
+func TestHandleSuggestModelFiltersByBudget(t *testing.T) {
+	_, out, err := handleSuggestModel(context.Background(), nil, SuggestModelInput{
+		Prompt:  "hello",
+		MaxSats: 1,
+	})
+	if err != nil {
+		t.Fatalf("handleSuggestModel: %v", err)
+	}
+	// Estimates are floored at 1 sat, so only gpt-3.5-turbo (~1.8 sats,
+	// truncated to 1) stays within a 1-sat budget; every other model costs more.
+	if len(out.Candidates) != 1 || out.Candidates[0].Model != "gpt-3.5-turbo" {
+		t.Fatalf("expected a 1-sat budget to leave only gpt-3.5-turbo, got %+v", out.Candidates)
+	}
+}
+
+func TestHandleSuggestModelRanksCheapestFirst(t *testing.T) {
+	_, out, err := handleSuggestModel(context.Background(), nil, SuggestModelInput{
+		Prompt: "hello",
+	})
+	if err != nil {
+		t.Fatalf("handleSuggestModel: %v", err)
+	}
+	for i := 1; i < len(out.Candidates); i++ {
+		if out.Candidates[i].EstimatedSats < out.Candidates[i-1].EstimatedSats {
+			t.Fatalf("candidates not sorted by ascending cost: %+v", out.Candidates)
+		}
+	}
+}
+
+func TestHandleSuggestModelExcludedProviders(t *testing.T) {
+	_, out, err := handleSuggestModel(context.Background(), nil, SuggestModelInput{
+		Prompt:            "hello",
+		ExcludedProviders: []string{"openai"},
+	})
+	if err != nil {
+		t.Fatalf("handleSuggestModel: %v", err)
+	}
+	for _, c := range out.Candidates {
+		if c.Provider == "openai" {
+			t.Fatalf("expected openai to be excluded, got %+v", out.Candidates)
+		}
+	}
+}
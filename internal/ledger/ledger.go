@@ -0,0 +1,122 @@
+// Package ledger records completed LightningProx charges to an append-only
+// JSONL file for cost attribution, borrowing the custom-records idea from
+// lncli's `--data type=hexvalue` flag: callers can tag a charge with
+// arbitrary metadata (agent_id, task_id, tenant, ...) and later query or
+// export spend grouped by it.
+package ledger
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Entry is a single recorded charge.
+type Entry struct {
+	Timestamp  time.Time         `json:"ts"`
+	ChargeID   string            `json:"charge_id,omitempty"`
+	Model      string            `json:"model,omitempty"`
+	AmountSats int               `json:"sats,omitempty"`
+	AmountUSD  float64           `json:"usd,omitempty"`
+	Metadata   map[string]string `json:"metadata,omitempty"`
+	Status     string            `json:"status"`
+}
+
+// Ledger is an append-only, JSONL-backed record of charges. It is safe for
+// concurrent use.
+type Ledger struct {
+	mu      sync.Mutex
+	path    string
+	entries []Entry
+}
+
+// DefaultPath returns $XDG_STATE_HOME/lightningprox-mcp/spend-ledger.jsonl,
+// falling back to ~/.local/state when XDG_STATE_HOME is unset.
+func DefaultPath() (string, error) {
+	base := os.Getenv("XDG_STATE_HOME")
+	if base == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve home directory: %w", err)
+		}
+		base = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(base, "lightningprox-mcp", "spend-ledger.jsonl"), nil
+}
+
+// Open loads existing entries from path, if it exists. An empty path yields
+// an in-memory-only ledger (writes succeed but nothing is persisted).
+func Open(path string) (*Ledger, error) {
+	l := &Ledger{path: path}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return l, nil
+		}
+		return nil, fmt.Errorf("failed to open spend ledger: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(line, &e); err != nil {
+			return nil, fmt.Errorf("failed to parse spend ledger: %w", err)
+		}
+		l.entries = append(l.entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read spend ledger: %w", err)
+	}
+	return l, nil
+}
+
+// Record appends entry to the ledger, persisting it immediately.
+func (l *Ledger) Record(entry Entry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.entries = append(l.entries, entry)
+	if l.path == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o700); err != nil {
+		return fmt.Errorf("failed to create spend ledger directory: %w", err)
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal spend ledger entry: %w", err)
+	}
+
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open spend ledger: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to append to spend ledger: %w", err)
+	}
+	return nil
+}
+
+// List returns a copy of all recorded entries, oldest first (append order).
+func (l *Ledger) List() []Entry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := make([]Entry, len(l.entries))
+	copy(out, l.entries)
+	return out
+}
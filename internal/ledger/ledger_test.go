@@ -0,0 +1,59 @@
+package ledger
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRecordPersistsAcrossReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spend-ledger.jsonl")
+
+	l, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+
+	entry := Entry{
+		Timestamp:  time.Now(),
+		ChargeID:   "ch_1",
+		Model:      "claude-sonnet-4-20250514",
+		AmountSats: 10,
+		AmountUSD:  0.01,
+		Metadata:   map[string]string{"tenant": "acme"},
+		Status:     "success",
+	}
+	if err := l.Record(entry); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+
+	reloaded, err := Open(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	list := reloaded.List()
+	if len(list) != 1 {
+		t.Fatalf("expected 1 entry after reload, got %d", len(list))
+	}
+	if list[0].ChargeID != "ch_1" || list[0].Metadata["tenant"] != "acme" {
+		t.Fatalf("unexpected entry after reload: %+v", list[0])
+	}
+}
+
+func TestRecordAppendsInOrder(t *testing.T) {
+	l, err := Open("")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if err := l.Record(Entry{ChargeID: "first", Status: "success"}); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+	if err := l.Record(Entry{ChargeID: "second", Status: "success"}); err != nil {
+		t.Fatalf("Record: %v", err)
+	}
+
+	list := l.List()
+	if len(list) != 2 || list[0].ChargeID != "first" || list[1].ChargeID != "second" {
+		t.Fatalf("unexpected order: %+v", list)
+	}
+}
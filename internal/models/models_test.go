@@ -0,0 +1,44 @@
+package models
+
+import "testing"
+
+func TestGetOrDefault(t *testing.T) {
+	m := GetOrDefault("")
+	if m.ID != DefaultModel {
+		t.Fatalf("expected default model, got %q", m.ID)
+	}
+
+	m = GetOrDefault("gpt-4-turbo")
+	if m.ID != "gpt-4-turbo" {
+		t.Fatalf("expected gpt-4-turbo, got %q", m.ID)
+	}
+
+	m = GetOrDefault("does-not-exist")
+	if m.ID != DefaultModel {
+		t.Fatalf("expected fallback to default model, got %q", m.ID)
+	}
+}
+
+func TestEstimateCostIncludesMarkup(t *testing.T) {
+	m, _ := Get("gpt-3.5-turbo")
+	sats, usd := EstimateCost(m, 1000, 1000, 100000.0)
+	wantUSD := m.InputCostPer1K*(1+Markup) + m.OutputCostPer1K*(1+Markup)
+	if usd != wantUSD {
+		t.Fatalf("expected usd %v, got %v", wantUSD, usd)
+	}
+	if sats < 1 {
+		t.Fatalf("expected at least 1 sat, got %d", sats)
+	}
+}
+
+func TestEstimateTokens(t *testing.T) {
+	if got := EstimateTokens(""); got != 0 {
+		t.Fatalf("expected 0 tokens for empty string, got %d", got)
+	}
+	if got := EstimateTokens("a"); got != 1 {
+		t.Fatalf("expected at least 1 token for non-empty string, got %d", got)
+	}
+	if got := EstimateTokens("abcdefgh"); got != 2 {
+		t.Fatalf("expected ~4 chars per token, got %d", got)
+	}
+}
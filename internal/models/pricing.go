@@ -0,0 +1,25 @@
+package models
+
+// Markup is LightningProx's markup over the underlying provider's price.
+const Markup = 0.20
+
+// PricedInputCostPer1K returns m's input cost including LightningProx's markup.
+func (m Model) PricedInputCostPer1K() float64 {
+	return m.InputCostPer1K * (1 + Markup)
+}
+
+// PricedOutputCostPer1K returns m's output cost including LightningProx's markup.
+func (m Model) PricedOutputCostPer1K() float64 {
+	return m.OutputCostPer1K * (1 + Markup)
+}
+
+// EstimateCost estimates the cost of a request to m given its input and
+// expected output token counts, returning both sats (at btcPriceUSD) and USD.
+func EstimateCost(m Model, inputTokens, outputTokens int, btcPriceUSD float64) (sats int, usd float64) {
+	usd = (float64(inputTokens)/1000.0)*m.PricedInputCostPer1K() + (float64(outputTokens)/1000.0)*m.PricedOutputCostPer1K()
+	sats = int((usd / btcPriceUSD) * 100_000_000)
+	if sats < 1 {
+		sats = 1
+	}
+	return sats, usd
+}
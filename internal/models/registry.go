@@ -0,0 +1,82 @@
+// Package models is the registry of AI models LightningProx supports, plus
+// the cost-estimation and token-counting helpers used to rank them.
+package models
+
+// Model describes one model's base (pre-markup) pricing, context window, and
+// a static quality score used by suggest_model to rank candidates.
+type Model struct {
+	ID              string  `json:"id"`
+	Provider        string  `json:"provider"`
+	InputCostPer1K  float64 `json:"input_cost_per_1k_tokens"`
+	OutputCostPer1K float64 `json:"output_cost_per_1k_tokens"`
+	MaxContext      int     `json:"max_context_tokens"`
+	// QualityScore is a static, hand-assigned 0-1 estimate of output quality
+	// relative to the other registered models. It isn't measured per-request.
+	QualityScore float64 `json:"quality_score"`
+}
+
+// registry mirrors isValidModel() on the LightningProx backend.
+var registry = []Model{
+	{
+		ID:              "claude-sonnet-4-20250514",
+		Provider:        "anthropic",
+		InputCostPer1K:  0.003,
+		OutputCostPer1K: 0.015,
+		MaxContext:      200000,
+		QualityScore:    0.95,
+	},
+	{
+		ID:              "claude-3-5-sonnet-20241022",
+		Provider:        "anthropic",
+		InputCostPer1K:  0.003,
+		OutputCostPer1K: 0.015,
+		MaxContext:      200000,
+		QualityScore:    0.90,
+	},
+	{
+		ID:              "gpt-4-turbo",
+		Provider:        "openai",
+		InputCostPer1K:  0.01,
+		OutputCostPer1K: 0.03,
+		MaxContext:      128000,
+		QualityScore:    0.92,
+	},
+	{
+		ID:              "gpt-3.5-turbo",
+		Provider:        "openai",
+		InputCostPer1K:  0.0005,
+		OutputCostPer1K: 0.0015,
+		MaxContext:      16385,
+		QualityScore:    0.55,
+	},
+}
+
+// DefaultModel is used when a caller doesn't specify one.
+const DefaultModel = "claude-sonnet-4-20250514"
+
+// All returns every registered model.
+func All() []Model {
+	return append([]Model(nil), registry...)
+}
+
+// Get looks up a model by ID.
+func Get(id string) (Model, bool) {
+	for _, m := range registry {
+		if m.ID == id {
+			return m, true
+		}
+	}
+	return Model{}, false
+}
+
+// GetOrDefault looks up a model by ID, falling back to DefaultModel if id is
+// empty or unrecognized.
+func GetOrDefault(id string) Model {
+	if id != "" {
+		if m, ok := Get(id); ok {
+			return m
+		}
+	}
+	m, _ := Get(DefaultModel)
+	return m
+}
@@ -0,0 +1,16 @@
+package models
+
+// EstimateTokens is a lightweight stand-in for a real tiktoken BPE tokenizer.
+// It approximates GPT-style token counts at ~4 characters per token, which is
+// accurate enough to tell whether a prompt is anywhere near a model's
+// MaxContext without shipping a full BPE vocabulary.
+func EstimateTokens(text string) int {
+	if text == "" {
+		return 0
+	}
+	n := len(text) / 4
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
@@ -0,0 +1,211 @@
+// Package payments tracks the lifecycle of LightningProx charges across MCP
+// calls, modeled on lnd's ControlTower: every charge moves through Created,
+// InFlight, Succeeded, Failed, or Expired. State is persisted to disk so it
+// survives server restarts, since MCP clients reconnect often.
+package payments
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// State is a stage in a charge's lifecycle.
+type State string
+
+const (
+	StateCreated   State = "created"
+	StateInFlight  State = "in_flight"
+	StateSucceeded State = "succeeded"
+	StateFailed    State = "failed"
+	StateExpired   State = "expired"
+)
+
+// Terminal reports whether state is an end state that no longer transitions.
+func (s State) Terminal() bool {
+	switch s {
+	case StateSucceeded, StateFailed, StateExpired:
+		return true
+	default:
+		return false
+	}
+}
+
+// Entry is the tracked state of a single charge.
+type Entry struct {
+	ChargeID    string     `json:"charge_id"`
+	State       State      `json:"state"`
+	Model       string     `json:"model,omitempty"`
+	AmountSats  int        `json:"amount_sats,omitempty"`
+	AmountUSD   float64    `json:"amount_usd,omitempty"`
+	Attempts    int        `json:"attempts"`
+	LastErr     string     `json:"last_err,omitempty"`
+	FirstSeen   time.Time  `json:"first_seen"`
+	SettledAt   *time.Time `json:"settled_at,omitempty"`
+	Preimage    string     `json:"preimage,omitempty"`
+	PaymentHash string     `json:"payment_hash,omitempty"`
+	// Consumed marks that a settled payment's AI response was already
+	// returned to the caller, so resume_payment won't replay it twice.
+	Consumed bool `json:"consumed"`
+}
+
+// Store is a JSON-file-backed, charge_id-keyed record of payment state.
+// It is safe for concurrent use.
+type Store struct {
+	mu      sync.Mutex
+	path    string
+	entries map[string]*Entry
+}
+
+// DefaultPath returns $XDG_STATE_HOME/lightningprox-mcp/payments.json,
+// falling back to ~/.local/state when XDG_STATE_HOME is unset.
+func DefaultPath() (string, error) {
+	base := os.Getenv("XDG_STATE_HOME")
+	if base == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve home directory: %w", err)
+		}
+		base = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(base, "lightningprox-mcp", "payments.json"), nil
+}
+
+// Open loads the store at path, if it exists. An empty path yields an
+// in-memory-only store (writes succeed but nothing is persisted).
+func Open(path string) (*Store, error) {
+	s := &Store{path: path, entries: make(map[string]*Entry)}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, fmt.Errorf("failed to read payment store: %w", err)
+	}
+
+	var list []*Entry
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse payment store: %w", err)
+	}
+	for _, e := range list {
+		s.entries[e.ChargeID] = e
+	}
+	return s, nil
+}
+
+func (s *Store) saveLocked() error {
+	if s.path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return fmt.Errorf("failed to create payment store directory: %w", err)
+	}
+
+	list := make([]*Entry, 0, len(s.entries))
+	for _, e := range s.entries {
+		list = append(list, e)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].FirstSeen.Before(list[j].FirstSeen) })
+
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal payment store: %w", err)
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write payment store: %w", err)
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// Get returns a copy of the entry for chargeID, if tracked.
+func (s *Store) Get(chargeID string) (Entry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[chargeID]
+	if !ok {
+		return Entry{}, false
+	}
+	return *e, true
+}
+
+// List returns a copy of all tracked entries, oldest first.
+func (s *Store) List() []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := make([]Entry, 0, len(s.entries))
+	for _, e := range s.entries {
+		list = append(list, *e)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].FirstSeen.Before(list[j].FirstSeen) })
+	return list
+}
+
+// Upsert inserts or replaces the entry for entry.ChargeID and persists it.
+func (s *Store) Upsert(entry Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cp := entry
+	s.entries[entry.ChargeID] = &cp
+	return s.saveLocked()
+}
+
+// Transition moves an already-tracked charge to a new state, bumping its
+// attempt count and recording settledAt if the new state is terminal. It
+// errors if chargeID isn't tracked yet — callers should Upsert first.
+func (s *Store) Transition(chargeID string, state State, lastErr string) (Entry, error) {
+	return s.transition(chargeID, state, "", lastErr)
+}
+
+// Settle is Transition plus recording the payment preimage, for the
+// successful-payment case.
+func (s *Store) Settle(chargeID string, preimage string) (Entry, error) {
+	return s.transition(chargeID, StateSucceeded, preimage, "")
+}
+
+func (s *Store) transition(chargeID string, state State, preimage, lastErr string) (Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[chargeID]
+	if !ok {
+		return Entry{}, fmt.Errorf("unknown charge_id %q", chargeID)
+	}
+
+	e.State = state
+	e.Attempts++
+	if preimage != "" {
+		e.Preimage = preimage
+	}
+	if lastErr != "" {
+		e.LastErr = lastErr
+	}
+	if state.Terminal() && e.SettledAt == nil {
+		now := time.Now()
+		e.SettledAt = &now
+	}
+
+	if err := s.saveLocked(); err != nil {
+		return Entry{}, err
+	}
+	return *e, nil
+}
+
+// MarkConsumed flags a settled charge's AI response as already delivered,
+// so resume_payment refuses to replay it.
+func (s *Store) MarkConsumed(chargeID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[chargeID]
+	if !ok {
+		return fmt.Errorf("unknown charge_id %q", chargeID)
+	}
+	e.Consumed = true
+	return s.saveLocked()
+}
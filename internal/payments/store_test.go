@@ -0,0 +1,70 @@
+package payments
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestUpsertAndTransitionPersist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "payments.json")
+
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+
+	entry := Entry{ChargeID: "ch_1", State: StateCreated, Model: "claude-sonnet-4-20250514", AmountSats: 10, FirstSeen: time.Now()}
+	if err := s.Upsert(entry); err != nil {
+		t.Fatalf("Upsert: %v", err)
+	}
+
+	if _, err := s.Transition("ch_1", StateSucceeded, ""); err != nil {
+		t.Fatalf("Transition: %v", err)
+	}
+
+	// Reload from disk to confirm persistence.
+	reloaded, err := Open(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	got, ok := reloaded.Get("ch_1")
+	if !ok {
+		t.Fatal("expected ch_1 to be tracked after reload")
+	}
+	if got.State != StateSucceeded {
+		t.Fatalf("expected state succeeded, got %q", got.State)
+	}
+	if got.SettledAt == nil {
+		t.Fatal("expected settled_at to be set for a terminal state")
+	}
+}
+
+func TestTransitionUnknownCharge(t *testing.T) {
+	s, err := Open("")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if _, err := s.Transition("missing", StateSucceeded, ""); err == nil {
+		t.Fatal("expected an error transitioning an untracked charge")
+	}
+}
+
+func TestListOrdering(t *testing.T) {
+	s, err := Open("")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	now := time.Now()
+	if err := s.Upsert(Entry{ChargeID: "second", FirstSeen: now.Add(time.Second)}); err != nil {
+		t.Fatalf("Upsert: %v", err)
+	}
+	if err := s.Upsert(Entry{ChargeID: "first", FirstSeen: now}); err != nil {
+		t.Fatalf("Upsert: %v", err)
+	}
+
+	list := s.List()
+	if len(list) != 2 || list[0].ChargeID != "first" || list[1].ChargeID != "second" {
+		t.Fatalf("unexpected order: %+v", list)
+	}
+}
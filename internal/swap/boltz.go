@@ -0,0 +1,156 @@
+package swap
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// boltzProvider speaks the Boltz v2 submarine swap REST API: POST
+// /v2/swap/submarine creates a swap that pays invoice once the returned
+// address confirms on-chain; GET /v2/swap/{id} reports its status.
+type boltzProvider struct {
+	baseURL string
+	client  *http.Client
+}
+
+func newBoltzProvider(cfg Config) *boltzProvider {
+	return &boltzProvider{
+		baseURL: strings.TrimRight(cfg.ProviderURL, "/"),
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (b *boltzProvider) Name() string { return "boltz" }
+
+func (b *boltzProvider) GetQuote(ctx context.Context, amountSats int) (Quote, error) {
+	respBody, err := b.do(ctx, "GET", "/v2/swap/submarine/"+fmt.Sprint(amountSats), nil)
+	if err != nil {
+		return Quote{}, err
+	}
+
+	var out struct {
+		MinerFeeSat int `json:"miner_fee_sat"`
+	}
+	if err := json.Unmarshal(respBody, &out); err != nil {
+		return Quote{}, fmt.Errorf("failed to parse boltz quote: %w", err)
+	}
+
+	const routingBufferSat = 50
+	return Quote{
+		AmountSat:        amountSats,
+		RoutingBufferSat: routingBufferSat,
+		EstimatedFeeSat:  out.MinerFeeSat,
+		TotalSat:         amountSats + routingBufferSat + out.MinerFeeSat,
+	}, nil
+}
+
+func (b *boltzProvider) SubmitSwap(ctx context.Context, invoice string, amountSats int) (Result, error) {
+	reqBody := map[string]interface{}{
+		"invoice": invoice,
+		"from":    "BTC",
+		"to":      "BTC",
+	}
+	respBody, err := b.do(ctx, "POST", "/v2/swap/submarine", reqBody)
+	if err != nil {
+		return Result{}, err
+	}
+
+	var out struct {
+		ID                 string `json:"id"`
+		Address            string `json:"address"`
+		ExpectedAmount     int    `json:"expectedAmount"`
+		TimeoutBlockHeight int    `json:"timeoutBlockHeight"`
+	}
+	if err := json.Unmarshal(respBody, &out); err != nil {
+		return Result{}, fmt.Errorf("failed to parse boltz swap response: %w", err)
+	}
+
+	return Result{
+		SwapID:         out.ID,
+		Address:        out.Address,
+		ExpectedFeeSat: out.ExpectedAmount - amountSats,
+		ExpiresAt:      time.Now().Add(1 * time.Hour), // fixed 1-hour estimate; timeoutBlockHeight is not used
+	}, nil
+}
+
+func (b *boltzProvider) CheckStatus(ctx context.Context, swapID string) (Status, error) {
+	respBody, err := b.do(ctx, "GET", "/v2/swap/"+swapID, nil)
+	if err != nil {
+		return Status{}, err
+	}
+
+	var out struct {
+		Status          string `json:"status"`
+		TransactionInfo struct {
+			Preimage string `json:"preimage"`
+		} `json:"transaction,omitempty"`
+	}
+	if err := json.Unmarshal(respBody, &out); err != nil {
+		return Status{}, fmt.Errorf("failed to parse boltz status: %w", err)
+	}
+
+	return Status{
+		SwapID:   swapID,
+		State:    boltzStateToStatus(out.Status),
+		Preimage: out.TransactionInfo.Preimage,
+	}, nil
+}
+
+// boltzStateToStatus maps Boltz's swap status strings onto our smaller,
+// backend-agnostic state set.
+func boltzStateToStatus(boltzStatus string) string {
+	switch boltzStatus {
+	case "swap.created", "invoice.set":
+		return "pending"
+	case "transaction.mempool":
+		return "mempool"
+	case "transaction.confirmed":
+		return "confirmed"
+	case "invoice.paid", "transaction.claimed":
+		return "paid"
+	case "swap.expired", "invoice.expired":
+		return "expired"
+	case "transaction.failed", "invoice.failedToPay":
+		return "failed"
+	default:
+		return "pending"
+	}
+}
+
+func (b *boltzProvider) do(ctx context.Context, method, path string, body interface{}) ([]byte, error) {
+	var reqBody io.Reader
+	if body != nil {
+		jsonBytes, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reqBody = bytes.NewReader(jsonBytes)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, b.baseURL+path, reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("boltz request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read boltz response: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("boltz returned HTTP %d: %s", resp.StatusCode, string(respBody))
+	}
+	return respBody, nil
+}
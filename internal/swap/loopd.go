@@ -0,0 +1,138 @@
+package swap
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// loopdProvider talks to LND's loopd daemon over its REST gateway
+// (SWAP_PROVIDER_URL), using the same loop-in RPCs as `loop in`.
+type loopdProvider struct {
+	baseURL string
+	client  *http.Client
+}
+
+func newLoopdProvider(cfg Config) *loopdProvider {
+	return &loopdProvider{
+		baseURL: strings.TrimRight(cfg.ProviderURL, "/"),
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (l *loopdProvider) Name() string { return "loopd" }
+
+func (l *loopdProvider) GetQuote(ctx context.Context, amountSats int) (Quote, error) {
+	respBody, err := l.do(ctx, "GET", fmt.Sprintf("/v1/loop/in/quote/%d", amountSats), nil)
+	if err != nil {
+		return Quote{}, err
+	}
+
+	var out struct {
+		MinerFeeSat int64 `json:"miner_fee_sat,string"`
+	}
+	if err := json.Unmarshal(respBody, &out); err != nil {
+		return Quote{}, fmt.Errorf("failed to parse loopd quote: %w", err)
+	}
+
+	const routingBufferSat = 50
+	return Quote{
+		AmountSat:        amountSats,
+		RoutingBufferSat: routingBufferSat,
+		EstimatedFeeSat:  int(out.MinerFeeSat),
+		TotalSat:         amountSats + routingBufferSat + int(out.MinerFeeSat),
+	}, nil
+}
+
+func (l *loopdProvider) SubmitSwap(ctx context.Context, invoice string, amountSats int) (Result, error) {
+	reqBody := map[string]interface{}{
+		"amt":     amountSats,
+		"invoice": invoice,
+	}
+	respBody, err := l.do(ctx, "POST", "/v1/loop/in", reqBody)
+	if err != nil {
+		return Result{}, err
+	}
+
+	var out struct {
+		ID          string `json:"id"`
+		HtlcAddress string `json:"htlc_address"`
+	}
+	if err := json.Unmarshal(respBody, &out); err != nil {
+		return Result{}, fmt.Errorf("failed to parse loopd response: %w", err)
+	}
+
+	return Result{
+		SwapID:    out.ID,
+		Address:   out.HtlcAddress,
+		ExpiresAt: time.Now().Add(1 * time.Hour),
+	}, nil
+}
+
+func (l *loopdProvider) CheckStatus(ctx context.Context, swapID string) (Status, error) {
+	respBody, err := l.do(ctx, "GET", "/v1/loop/"+swapID, nil)
+	if err != nil {
+		return Status{}, err
+	}
+
+	var out struct {
+		State string `json:"state"`
+	}
+	if err := json.Unmarshal(respBody, &out); err != nil {
+		return Status{}, fmt.Errorf("failed to parse loopd status: %w", err)
+	}
+
+	return Status{SwapID: swapID, State: loopdStateToStatus(out.State)}, nil
+}
+
+func loopdStateToStatus(loopdState string) string {
+	switch loopdState {
+	case "INITIATED", "PREIMAGE_REVEALED":
+		return "pending"
+	case "HTLC_PUBLISHED":
+		return "mempool"
+	case "SUCCESS":
+		return "paid"
+	case "FAILED":
+		return "failed"
+	default:
+		return "pending"
+	}
+}
+
+func (l *loopdProvider) do(ctx context.Context, method, path string, body interface{}) ([]byte, error) {
+	var reqBody io.Reader
+	if body != nil {
+		jsonBytes, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reqBody = bytes.NewReader(jsonBytes)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, l.baseURL+path, reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("loopd request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read loopd response: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("loopd returned HTTP %d: %s", resp.StatusCode, string(respBody))
+	}
+	return respBody, nil
+}
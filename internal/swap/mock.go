@@ -0,0 +1,52 @@
+package swap
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// MockProvider is an in-memory Provider used by tests. It quotes a fixed fee,
+// "creates" swaps instantly with a deterministic address, and reports
+// whatever state the caller has set for a swap ID in States ("failed" for an
+// unknown swap ID).
+type MockProvider struct {
+	FeeSat int
+	States map[string]Status
+}
+
+func NewMockProvider() *MockProvider {
+	return &MockProvider{FeeSat: 100, States: make(map[string]Status)}
+}
+
+func (m *MockProvider) Name() string { return "mock" }
+
+func (m *MockProvider) GetQuote(ctx context.Context, amountSats int) (Quote, error) {
+	const routingBufferSat = 50
+	return Quote{
+		AmountSat:        amountSats,
+		RoutingBufferSat: routingBufferSat,
+		EstimatedFeeSat:  m.FeeSat,
+		TotalSat:         amountSats + routingBufferSat + m.FeeSat,
+	}, nil
+}
+
+func (m *MockProvider) SubmitSwap(ctx context.Context, invoice string, amountSats int) (Result, error) {
+	sum := sha256.Sum256([]byte(invoice))
+	swapID := hex.EncodeToString(sum[:8])
+	m.States[swapID] = Status{SwapID: swapID, State: "pending"}
+	return Result{
+		SwapID:         swapID,
+		Address:        "bcrt1q" + hex.EncodeToString(sum[:16]),
+		ExpectedFeeSat: m.FeeSat,
+		ExpiresAt:      time.Now().Add(time.Hour),
+	}, nil
+}
+
+func (m *MockProvider) CheckStatus(ctx context.Context, swapID string) (Status, error) {
+	if s, ok := m.States[swapID]; ok {
+		return s, nil
+	}
+	return Status{SwapID: swapID, State: "failed"}, nil
+}
@@ -0,0 +1,78 @@
+// Package swap provides an on-chain-to-Lightning submarine swap fallback
+// (Loop-in style) so an agent whose only funds are on-chain BTC can still
+// settle a LightningProx invoice. Providers are pluggable: a Boltz-compatible
+// HTTP API, or an LND loopd daemon.
+package swap
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Quote is a fee estimate for swapping amountSats of on-chain BTC into a
+// Lightning payment, before any swap is actually created.
+type Quote struct {
+	AmountSat        int `json:"amount_sat"`
+	RoutingBufferSat int `json:"routing_buffer_sat"`
+	EstimatedFeeSat  int `json:"estimated_fee_sat"`
+	TotalSat         int `json:"total_sat"`
+}
+
+// Result is a created swap: the on-chain address the caller must fund, and
+// what the provider will pay once that address confirms.
+type Result struct {
+	SwapID         string    `json:"swap_id"`
+	Address        string    `json:"address"`
+	ExpectedFeeSat int       `json:"expected_fee_sat"`
+	ExpiresAt      time.Time `json:"expires_at"`
+}
+
+// Status is the current state of a previously-submitted swap.
+type Status struct {
+	SwapID   string `json:"swap_id"`
+	State    string `json:"state"` // "pending", "mempool", "confirmed", "paid", "failed", "expired"
+	Preimage string `json:"preimage,omitempty"`
+}
+
+// Provider is the swap-service surface needed for loop-in style swaps.
+// boltzProvider and loopdProvider implement it against real services;
+// MockProvider implements it for tests.
+type Provider interface {
+	Name() string
+	GetQuote(ctx context.Context, amountSats int) (Quote, error)
+	SubmitSwap(ctx context.Context, invoice string, amountSats int) (Result, error)
+	CheckStatus(ctx context.Context, swapID string) (Status, error)
+}
+
+// Config holds the env-driven settings for the swap fallback.
+type Config struct {
+	ProviderURL string // SWAP_PROVIDER_URL
+	Provider    string // SWAP_PROVIDER: "boltz" (default) or "loopd"
+}
+
+// ConfigFromEnv reads the swap configuration from the process environment.
+func ConfigFromEnv() Config {
+	return Config{
+		ProviderURL: os.Getenv("SWAP_PROVIDER_URL"),
+		Provider:    os.Getenv("SWAP_PROVIDER"),
+	}
+}
+
+// NewProvider constructs the Provider selected by cfg. It returns (nil, nil)
+// when no SWAP_PROVIDER_URL is configured, meaning the swap fallback is
+// disabled.
+func NewProvider(cfg Config) (Provider, error) {
+	if cfg.ProviderURL == "" {
+		return nil, nil
+	}
+	switch cfg.Provider {
+	case "", "boltz":
+		return newBoltzProvider(cfg), nil
+	case "loopd":
+		return newLoopdProvider(cfg), nil
+	default:
+		return nil, fmt.Errorf("unknown SWAP_PROVIDER %q (want boltz or loopd)", cfg.Provider)
+	}
+}
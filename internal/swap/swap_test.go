@@ -0,0 +1,61 @@
+package swap
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMockProviderQuoteAndSubmit(t *testing.T) {
+	m := NewMockProvider()
+
+	quote, err := m.GetQuote(context.Background(), 1000)
+	if err != nil {
+		t.Fatalf("GetQuote: %v", err)
+	}
+	if quote.TotalSat <= quote.AmountSat {
+		t.Fatalf("expected total to include fee and buffer, got %+v", quote)
+	}
+
+	result, err := m.SubmitSwap(context.Background(), "lnbc1...", 1000)
+	if err != nil {
+		t.Fatalf("SubmitSwap: %v", err)
+	}
+	if result.SwapID == "" || result.Address == "" {
+		t.Fatalf("expected a swap id and address, got %+v", result)
+	}
+
+	status, err := m.CheckStatus(context.Background(), result.SwapID)
+	if err != nil {
+		t.Fatalf("CheckStatus: %v", err)
+	}
+	if status.State != "pending" {
+		t.Fatalf("expected pending, got %q", status.State)
+	}
+}
+
+func TestCheckStatusUnknownSwap(t *testing.T) {
+	m := NewMockProvider()
+	status, err := m.CheckStatus(context.Background(), "missing")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status.State != "failed" {
+		t.Fatalf("expected failed for an unknown swap, got %q", status.State)
+	}
+}
+
+func TestNewProviderDisabled(t *testing.T) {
+	p, err := NewProvider(Config{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p != nil {
+		t.Fatal("expected nil provider when SWAP_PROVIDER_URL is unset")
+	}
+}
+
+func TestNewProviderUnknown(t *testing.T) {
+	if _, err := NewProvider(Config{ProviderURL: "https://example.com", Provider: "bogus"}); err == nil {
+		t.Fatal("expected an error for an unknown provider")
+	}
+}
@@ -0,0 +1,95 @@
+// Package swapstate persists the mapping from a submarine swap's swap_id to
+// the LightningProx charge_id it is settling. Boltz/loopd track a swap's own
+// status server-side, so check_swap_status keeps working across a restart,
+// but the local swap_id -> charge_id link does not: on-chain confirmations
+// often take far longer than a restart, and without this link a swap that
+// completes after one can never settle its charge.
+package swapstate
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Store is a JSON-file-backed, swap_id-keyed record of which charge_id a
+// swap is paying. It is safe for concurrent use.
+type Store struct {
+	mu      sync.Mutex
+	path    string
+	entries map[string]string
+}
+
+// DefaultPath returns $XDG_STATE_HOME/lightningprox-mcp/swap-charges.json,
+// falling back to ~/.local/state when XDG_STATE_HOME is unset.
+func DefaultPath() (string, error) {
+	base := os.Getenv("XDG_STATE_HOME")
+	if base == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve home directory: %w", err)
+		}
+		base = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(base, "lightningprox-mcp", "swap-charges.json"), nil
+}
+
+// Open loads the store at path, if it exists. An empty path yields an
+// in-memory-only store (writes succeed but nothing is persisted).
+func Open(path string) (*Store, error) {
+	s := &Store{path: path, entries: make(map[string]string)}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, fmt.Errorf("failed to read swap charge map: %w", err)
+	}
+	if err := json.Unmarshal(data, &s.entries); err != nil {
+		return nil, fmt.Errorf("failed to parse swap charge map: %w", err)
+	}
+	return s, nil
+}
+
+func (s *Store) saveLocked() error {
+	if s.path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return fmt.Errorf("failed to create swap charge map directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(s.entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal swap charge map: %w", err)
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write swap charge map: %w", err)
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// Track records that swapID is paying chargeID, if both are known, and
+// persists the mapping.
+func (s *Store) Track(swapID, chargeID string) error {
+	if swapID == "" || chargeID == "" {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[swapID] = chargeID
+	return s.saveLocked()
+}
+
+// ChargeID returns the charge_id tracked for swapID, if any.
+func (s *Store) ChargeID(swapID string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	chargeID, ok := s.entries[swapID]
+	return chargeID, ok
+}
@@ -0,0 +1,43 @@
+package swapstate
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestTrackPersistsAcrossReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "swap-charges.json")
+
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if err := s.Track("swap_1", "ch_1"); err != nil {
+		t.Fatalf("Track: %v", err)
+	}
+
+	reloaded, err := Open(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	chargeID, ok := reloaded.ChargeID("swap_1")
+	if !ok || chargeID != "ch_1" {
+		t.Fatalf("expected swap_1 -> ch_1 after reload, got %q, %v", chargeID, ok)
+	}
+}
+
+func TestTrackIgnoresEmptyIDs(t *testing.T) {
+	s, err := Open("")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if err := s.Track("", "ch_1"); err != nil {
+		t.Fatalf("Track: %v", err)
+	}
+	if err := s.Track("swap_1", ""); err != nil {
+		t.Fatalf("Track: %v", err)
+	}
+	if _, ok := s.ChargeID("swap_1"); ok {
+		t.Fatal("expected no mapping to be tracked for an empty swap_id or charge_id")
+	}
+}
@@ -0,0 +1,87 @@
+package wallet
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// BudgetTracker enforces per-call and per-day spend ceilings across autopay
+// payments. A zero ceiling means "no limit" for that dimension.
+type BudgetTracker struct {
+	mu          sync.Mutex
+	maxPerCall  int
+	dailyBudget int
+	day         string
+	spentToday  int
+}
+
+// NewBudgetTracker builds a tracker from the MAX_SATS_PER_CALL and
+// DAILY_SATS_BUDGET settings.
+func NewBudgetTracker(maxPerCall, dailyBudget int) *BudgetTracker {
+	return &BudgetTracker{maxPerCall: maxPerCall, dailyBudget: dailyBudget}
+}
+
+// Authorize checks amountSats against both ceilings and, if allowed, reserves
+// it against the daily budget. Callers that end up not spending the reserved
+// amount should call Release.
+func (b *BudgetTracker) Authorize(amountSats int) error {
+	if b.maxPerCall > 0 && amountSats > b.maxPerCall {
+		return fmt.Errorf("payment of %d sats exceeds MAX_SATS_PER_CALL (%d)", amountSats, b.maxPerCall)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rolloverLocked()
+
+	if b.dailyBudget > 0 && b.spentToday+amountSats > b.dailyBudget {
+		return fmt.Errorf("payment of %d sats would exceed DAILY_SATS_BUDGET (%d used of %d today)", amountSats, b.spentToday, b.dailyBudget)
+	}
+	b.spentToday += amountSats
+	return nil
+}
+
+// Release gives back sats that were authorized but never actually spent
+// (e.g. the payment failed after authorization).
+func (b *BudgetTracker) Release(amountSats int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rolloverLocked()
+	b.spentToday -= amountSats
+	if b.spentToday < 0 {
+		b.spentToday = 0
+	}
+}
+
+// SetCeilings updates the per-call and daily ceilings in place, leaving
+// spentToday untouched so a caller cannot reset its own accumulated spend by
+// re-issuing the same (or any) ceilings.
+func (b *BudgetTracker) SetCeilings(maxPerCall, dailyBudget int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.maxPerCall = maxPerCall
+	b.dailyBudget = dailyBudget
+}
+
+// Remaining returns sats left in today's budget, or -1 if unlimited.
+func (b *BudgetTracker) Remaining() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rolloverLocked()
+	if b.dailyBudget <= 0 {
+		return -1
+	}
+	remaining := b.dailyBudget - b.spentToday
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+func (b *BudgetTracker) rolloverLocked() {
+	today := time.Now().UTC().Format("2006-01-02")
+	if b.day != today {
+		b.day = today
+		b.spentToday = 0
+	}
+}
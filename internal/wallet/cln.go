@@ -0,0 +1,124 @@
+package wallet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sync/atomic"
+)
+
+// clnBackend talks to core-lightning over its lightning-rpc UNIX socket
+// using the node's JSON-RPC protocol (the same one `lightning-cli` uses).
+type clnBackend struct {
+	rpcPath string
+	nextID  int64
+}
+
+func newCLNBackend(cfg Config) (Backend, error) {
+	if cfg.CLNRPCPath == "" {
+		return nil, fmt.Errorf("CLN_RPC_PATH is required for LIGHTNING_BACKEND=cln")
+	}
+	return &clnBackend{rpcPath: cfg.CLNRPCPath}, nil
+}
+
+func (c *clnBackend) Name() string { return "cln" }
+
+type clnRequest struct {
+	JSONRPC string      `json:"jsonrpc"`
+	ID      int64       `json:"id"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+type clnError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (c *clnBackend) call(ctx context.Context, method string, params interface{}, result interface{}) error {
+	conn, err := (&net.Dialer{}).DialContext(ctx, "unix", c.rpcPath)
+	if err != nil {
+		return fmt.Errorf("failed to connect to CLN_RPC_PATH: %w", err)
+	}
+	defer conn.Close()
+
+	req := clnRequest{
+		JSONRPC: "2.0",
+		ID:      atomic.AddInt64(&c.nextID, 1),
+		Method:  method,
+		Params:  params,
+	}
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return fmt.Errorf("failed to send cln request: %w", err)
+	}
+
+	var resp struct {
+		Result json.RawMessage `json:"result"`
+		Error  *clnError       `json:"error"`
+	}
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return fmt.Errorf("failed to decode cln response: %w", err)
+	}
+	if resp.Error != nil {
+		return fmt.Errorf("cln %s failed: %s (code %d)", method, resp.Error.Message, resp.Error.Code)
+	}
+	if result != nil {
+		if err := json.Unmarshal(resp.Result, result); err != nil {
+			return fmt.Errorf("failed to parse cln %s result: %w", method, err)
+		}
+	}
+	return nil
+}
+
+func (c *clnBackend) GetInfo(ctx context.Context) (Info, error) {
+	var out struct {
+		Alias               string `json:"alias"`
+		BlockHeight         uint32 `json:"blockheight"`
+		WarningBitcoindSync string `json:"warning_bitcoind_sync"`
+	}
+	if err := c.call(ctx, "getinfo", struct{}{}, &out); err != nil {
+		return Info{}, err
+	}
+	return Info{
+		Backend:       "cln",
+		Alias:         out.Alias,
+		BlockHeight:   out.BlockHeight,
+		SyncedToChain: out.WarningBitcoindSync == "",
+	}, nil
+}
+
+func (c *clnBackend) Pay(ctx context.Context, invoice string, feeLimitSat, cltvLimit int) (PayResult, error) {
+	params := map[string]interface{}{
+		"bolt11": invoice,
+	}
+	if feeLimitSat > 0 {
+		params["maxfeepercent"] = 0
+		params["exemptfee"] = 0
+		params["maxfee"] = fmt.Sprintf("%dsat", feeLimitSat)
+	}
+	if cltvLimit > 0 {
+		params["maxdelay"] = cltvLimit
+	}
+
+	var out struct {
+		PaymentPreimage string `json:"payment_preimage"`
+		Status          string `json:"status"`
+		AmountSentMsat  int64  `json:"amount_sent_msat"`
+		AmountMsat      int64  `json:"amount_msat"`
+	}
+	if err := c.call(ctx, "pay", params, &out); err != nil {
+		return PayResult{Status: "failed"}, err
+	}
+
+	status := "succeeded"
+	if out.Status != "complete" {
+		status = "failed"
+	}
+	feeSat := int((out.AmountSentMsat - out.AmountMsat) / 1000)
+	return PayResult{
+		Preimage: out.PaymentPreimage,
+		FeeSat:   feeSat,
+		Status:   status,
+	}, nil
+}
@@ -0,0 +1,170 @@
+package wallet
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+)
+
+// lndBackend talks to LND's REST gateway (the same host:port most
+// deployments also expose gRPC on) using the admin macaroon for auth.
+type lndBackend struct {
+	addr     string
+	macaroon string
+	client   *http.Client
+}
+
+func newLNDBackend(cfg Config) (Backend, error) {
+	if cfg.LNDGRPCAddr == "" {
+		return nil, fmt.Errorf("LND_GRPC_ADDR is required for LIGHTNING_BACKEND=lnd")
+	}
+	if cfg.LNDAdminMacaroon == "" {
+		return nil, fmt.Errorf("LND_ADMIN_MACAROON is required for LIGHTNING_BACKEND=lnd")
+	}
+
+	tlsConfig := &tls.Config{}
+	if cfg.LNDTLSCert != "" {
+		pem, err := os.ReadFile(cfg.LNDTLSCert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read LND_TLS_CERT: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("failed to parse LND_TLS_CERT")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return &lndBackend{
+		addr:     cfg.LNDGRPCAddr,
+		macaroon: cfg.LNDAdminMacaroon,
+		client: &http.Client{
+			Transport: &http.Transport{TLSClientConfig: tlsConfig},
+		},
+	}, nil
+}
+
+func (l *lndBackend) Name() string { return "lnd" }
+
+func (l *lndBackend) do(ctx context.Context, method, path string, body interface{}) ([]byte, error) {
+	var reqBody io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reqBody = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, "https://"+l.addr+path, reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Grpc-Metadata-macaroon", l.macaroon)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("lnd request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lnd response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("lnd returned HTTP %d: %s", resp.StatusCode, string(respBody))
+	}
+	return respBody, nil
+}
+
+func (l *lndBackend) GetInfo(ctx context.Context) (Info, error) {
+	respBody, err := l.do(ctx, "GET", "/v1/getinfo", nil)
+	if err != nil {
+		return Info{}, err
+	}
+	var out struct {
+		Alias         string `json:"alias"`
+		BlockHeight   uint32 `json:"block_height"`
+		SyncedToChain bool   `json:"synced_to_chain"`
+	}
+	if err := json.Unmarshal(respBody, &out); err != nil {
+		return Info{}, fmt.Errorf("failed to parse getinfo response: %w", err)
+	}
+	return Info{
+		Backend:       "lnd",
+		Alias:         out.Alias,
+		BlockHeight:   out.BlockHeight,
+		SyncedToChain: out.SyncedToChain,
+	}, nil
+}
+
+// Pay drives LND's routerrpc SendPaymentV2 call via its REST equivalent,
+// which streams payment status updates as newline-delimited JSON. We read
+// until a terminal status (SUCCEEDED/FAILED) is observed.
+func (l *lndBackend) Pay(ctx context.Context, invoice string, feeLimitSat, cltvLimit int) (PayResult, error) {
+	reqBody := map[string]interface{}{
+		"payment_request":     invoice,
+		"fee_limit_sat":       feeLimitSat,
+		"cltv_limit":          cltvLimit,
+		"timeout_seconds":     60,
+		"no_inflight_updates": false,
+	}
+	b, err := json.Marshal(reqBody)
+	if err != nil {
+		return PayResult{}, fmt.Errorf("failed to marshal send request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://"+l.addr+"/v2/router/send", bytes.NewReader(b))
+	if err != nil {
+		return PayResult{}, fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Grpc-Metadata-macaroon", l.macaroon)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return PayResult{}, fmt.Errorf("lnd send failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var update struct {
+			Result struct {
+				Status        string `json:"status"`
+				Preimage      string `json:"payment_preimage"`
+				FeeSat        string `json:"fee_sat"`
+				FailureReason string `json:"failure_reason"`
+			} `json:"result"`
+		}
+		if err := dec.Decode(&update); err != nil {
+			if err == io.EOF {
+				return PayResult{Status: "failed"}, fmt.Errorf("lnd stream closed before a terminal status")
+			}
+			return PayResult{}, fmt.Errorf("failed to decode lnd payment update: %w", err)
+		}
+
+		switch update.Result.Status {
+		case "SUCCEEDED":
+			feeSat := 0
+			fmt.Sscanf(update.Result.FeeSat, "%d", &feeSat)
+			return PayResult{
+				Preimage: update.Result.Preimage,
+				FeeSat:   feeSat,
+				Status:   "succeeded",
+			}, nil
+		case "FAILED":
+			return PayResult{Status: "failed"}, fmt.Errorf("lnd payment failed: %s", update.Result.FailureReason)
+		default:
+			// IN_FLIGHT — keep reading the stream for the terminal update.
+		}
+	}
+}
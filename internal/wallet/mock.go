@@ -0,0 +1,37 @@
+package wallet
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// MockBackend is an in-memory Backend used by tests; NewBackend does not
+// construct it. It "pays" any invoice not listed in FailInvoices instantly
+// and deterministically derives a preimage from it.
+type MockBackend struct {
+	// FailInvoices, if set, causes Pay to fail for these invoice strings.
+	FailInvoices map[string]bool
+}
+
+func NewMockBackend() *MockBackend {
+	return &MockBackend{FailInvoices: make(map[string]bool)}
+}
+
+func (m *MockBackend) Name() string { return "mock" }
+
+func (m *MockBackend) GetInfo(ctx context.Context) (Info, error) {
+	return Info{Backend: "mock", Alias: "mock-node", SyncedToChain: true}, nil
+}
+
+func (m *MockBackend) Pay(ctx context.Context, invoice string, feeLimitSat, cltvLimit int) (PayResult, error) {
+	if m.FailInvoices[invoice] {
+		return PayResult{Status: "failed"}, nil
+	}
+	sum := sha256.Sum256([]byte(invoice))
+	return PayResult{
+		Preimage: hex.EncodeToString(sum[:]),
+		FeeSat:   0,
+		Status:   "succeeded",
+	}, nil
+}
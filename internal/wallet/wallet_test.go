@@ -0,0 +1,74 @@
+package wallet
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMockBackendPay(t *testing.T) {
+	m := NewMockBackend()
+	result, err := m.Pay(context.Background(), "lnbc1...", 10, 144)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Status != "succeeded" {
+		t.Fatalf("expected succeeded, got %q", result.Status)
+	}
+	if result.Preimage == "" {
+		t.Fatal("expected a non-empty preimage")
+	}
+}
+
+func TestMockBackendPayFailure(t *testing.T) {
+	m := NewMockBackend()
+	m.FailInvoices["lnbc-bad"] = true
+
+	result, err := m.Pay(context.Background(), "lnbc-bad", 10, 144)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Status != "failed" {
+		t.Fatalf("expected failed, got %q", result.Status)
+	}
+}
+
+func TestBudgetTrackerPerCallCeiling(t *testing.T) {
+	b := NewBudgetTracker(100, 0)
+	if err := b.Authorize(150); err == nil {
+		t.Fatal("expected per-call ceiling to reject payment")
+	}
+	if err := b.Authorize(50); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestBudgetTrackerDailyCeiling(t *testing.T) {
+	b := NewBudgetTracker(0, 100)
+	if err := b.Authorize(60); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := b.Authorize(60); err == nil {
+		t.Fatal("expected daily budget to reject second payment")
+	}
+
+	b.Release(60)
+	if err := b.Authorize(60); err != nil {
+		t.Fatalf("unexpected error after release: %v", err)
+	}
+}
+
+func TestNewBackendNone(t *testing.T) {
+	backend, err := NewBackend(Config{Backend: "none"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if backend != nil {
+		t.Fatal("expected nil backend when autopay is disabled")
+	}
+}
+
+func TestNewBackendUnknown(t *testing.T) {
+	if _, err := NewBackend(Config{Backend: "bogus"}); err == nil {
+		t.Fatal("expected an error for an unknown backend")
+	}
+}